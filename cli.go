@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"time"
+
+	"github.com/discordcalorietracker/command"
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/importer"
+)
+
+// runImport loads a nutrition dump into the local database, e.g
+// discordcalorietracker import -format off -path openfoodfacts-products.jsonl.gz
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "Format of the dump, either usda (FoodData Central CSV directory) or off (Open Food Facts JSONL)")
+	path := flags.String("path", "", "Path to the extracted USDA directory or the Open Food Facts JSONL file")
+	flags.String("db", "app.db", "SQLite file path or postgres:// connection URL")
+	configPath := flags.String("config", "", "Path to a YAML config file, can also be set with CALORIEBOT_CONFIG")
+	flags.Parse(args)
+
+	if *path == "" {
+		log.Fatalf("The -path flag is required")
+	}
+
+	cfg := loadConfig(flags, *configPath)
+	ctx, stop := rootContext()
+	defer stop()
+
+	database.InitDatabase(ctx, cfg.Database.DSN)
+	defer database.DB.Close()
+
+	start := time.Now()
+
+	var count int64
+	var err error
+	switch *format {
+	case "usda":
+		count, err = importer.ImportUSDA(ctx, database.DB, *path)
+	case "off":
+		count, err = importer.ImportOpenFoodFacts(ctx, database.DB, *path)
+	default:
+		log.Fatalf("Unknown import format %q, expected usda or off", *format)
+	}
+
+	if err != nil {
+		log.Fatalf("Could not import %v: %v", *path, err)
+	}
+
+	slog.Info("Imported nutrition entries", "count", count, "format", *format, "duration", time.Since(start).Round(time.Second))
+}
+
+// runMigrate shows or changes the schema version, e.g
+// discordcalorietracker migrate status|up|down [-to version] [-steps n]
+func runMigrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	to := flags.Int("to", 0, "Version to migrate up to, defaults to the latest")
+	steps := flags.Int("steps", 1, "Number of migrations to revert when migrating down")
+	flags.String("db", "app.db", "SQLite file path or postgres:// connection URL")
+	configPath := flags.String("config", "", "Path to a YAML config file, can also be set with CALORIEBOT_CONFIG")
+
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+		args = args[1:]
+	}
+	flags.Parse(args)
+
+	cfg := loadConfig(flags, *configPath)
+	ctx, stop := rootContext()
+	defer stop()
+
+	database.OpenDatabase(cfg.Database.DSN)
+	defer database.DB.Close()
+
+	var err error
+	switch action {
+	case "status":
+	case "up":
+		err = database.DB.MigrateUp(ctx, *to)
+	case "down":
+		err = database.DB.MigrateDown(ctx, *steps)
+	default:
+		log.Fatalf("Unknown migrate action %q, expected status, up or down", action)
+	}
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
+	current, err := database.DB.SchemaVersion(ctx)
+	if err != nil {
+		log.Fatalf("Could not read schema version: %v", err)
+	}
+
+	migrations, err := database.DB.Migrations()
+	if err != nil {
+		log.Fatalf("Could not load migrations: %v", err)
+	}
+
+	for _, migration := range migrations {
+		state := "pending"
+		if migration.Version <= current {
+			state = "applied"
+		}
+		fmt.Printf("%04d %-30s %s\n", migration.Version, migration.Name, state)
+	}
+	fmt.Printf("Schema version %d\n", current)
+}
+
+// runRegister updates the registered commands to match the current definitions without starting the bot, e.g
+// discordcalorietracker register -token <token> [-guild id]
+func runRegister(args []string) {
+	flags := flag.NewFlagSet("register", flag.ExitOnError)
+	flags.String("guild", "", "Guild to register the commands in. If not passed - commands are registered globally")
+	flags.String("token", "", "Bot access token")
+	flags.String("token-file", "", "File containing the bot access token, e.g a Docker secret")
+	configPath := flags.String("config", "", "Path to a YAML config file, can also be set with CALORIEBOT_CONFIG")
+	flags.Parse(args)
+
+	cfg := loadConfig(flags, *configPath)
+	requireToken(cfg)
+
+	// The definitions depend on the configured limits
+	command.Configure(cfg.Limits)
+	discord.InitDiscordSession(cfg.Discord.Token)
+	discord.InitDiscordCommands(command.CommandDefinitions, command.CommandHandlers)
+
+	changed, err := discord.SyncCommands(cfg.Discord.GuildID)
+	if err != nil {
+		log.Fatalf("Could not register commands: %v", err)
+	}
+	if changed {
+		fmt.Printf("Registered %d commands\n", len(command.CommandDefinitions))
+	} else {
+		fmt.Println("Commands are already up to date")
+	}
+}
@@ -1,71 +1,67 @@
 package command
 
 import (
-	"log"
+	"strings"
 	"time"
 
-	"github.com/bwmarrin/discordgo"
 	"github.com/discordcalorietracker/database"
 	"github.com/discordcalorietracker/discord"
 	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
 )
 
-func HandleAddCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userId := i.Member.User.ID
-	userDisplayName := i.Member.User.GlobalName
+func HandleAddCommand(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+	userDisplayName := c.User.GlobalName
+	user := c.Account
 
-	user, userErr := database.FetchUserByID(userId)
-	if userErr != nil {
-		log.Printf("Error fetching user with ID %v and username %v. Error: %v", userId, userDisplayName, userErr)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("Error fetching user, please try again...", true, nil))
+	foodItem := c.Options["fooditem"].StringValue()
+	calories, inRange := helper.ToKcal(c.Options["calories"].IntValue(), user.EnergyUnit)
+	if !inRange {
+		c.Respond(discord.CreateInteractionResponse(helper.OutOfRangeMessage(user.EnergyUnit), true, nil))
 		return
 	}
 
-	if (database.User{}) == user {
-		log.Printf("User with ID %v and username %v has tried to add without calling /set first.", userId, userDisplayName)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("Set your daily calories first using the /set command.", true, nil))
-		return
-	}
-
-	optionMap := helper.ConvertOptionsToMap(i)
-
-	foodItem := optionMap["fooditem"].StringValue()
-	calories := optionMap["calories"].IntValue()
-
 	foodLog := database.FoodLog{
 		UserID:   userId,
 		FoodItem: foodItem,
-		Calories: int16(calories),
+		Calories: calories,
 		Quantity: 1,
 	}
 
-	if quantity, ok := optionMap["quantity"]; ok {
-		itemQuantity := int16(quantity.IntValue())
-		foodLog.Quantity = itemQuantity
+	if quantityOpt, ok := c.Options["quantity"]; ok {
+		quantity, inRange := helper.RoundQuantity(quantityOpt.FloatValue())
+		if !inRange {
+			c.Respond(discord.CreateInteractionResponse(helper.QuantityOutOfRangeMessage(), true, nil))
+			return
+		}
+		foodLog.Quantity = quantity
+	}
+
+	if serving, ok := c.Options["serving"]; ok {
+		foodLog.Serving = strings.TrimSpace(serving.StringValue())
 	}
 
-	id, addFoodLogErr := database.AddUserFoodLog(&foodLog)
+	id, addFoodLogErr := helper.AddFoodLogAndUpdateStreak(c, c.Store, user, &foodLog)
 	if addFoodLogErr != nil {
-		log.Printf("Error adding food log for user with ID %v and username %v. Error: %v", userId, userDisplayName, addFoodLogErr)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		logger.Error("Error adding food log", "error", addFoodLogErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
 		return
 	}
 
-	lastLogged := user.LastLogged.Format(helper.DATEFORMAT)
-	currentDate := time.Now().Format(helper.DATEFORMAT)
-
-	if lastLogged != currentDate {
-		log.Printf("Updating the daily streak for user %v. They last logged on %v.", userDisplayName, lastLogged)
-		n, err := database.UpdateUserStreak(userId)
-		if n == 0 || err != nil {
-			log.Printf("Error updating daily streak for user %v. Error: %v", userDisplayName, err)
-			s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
-			return
-		}
+	// Remember the food so it can be matched by /log
+	savedFood := database.SavedFood{
+		UserID:   userId,
+		Name:     foodLog.FoodItem,
+		Calories: foodLog.Calories,
+	}
+	if saveErr := c.Store.SaveUserFood(c, &savedFood); saveErr != nil {
+		logger.Error("Error saving food", logging.FoodKey, foodLog.FoodItem, "error", saveErr)
 	}
 
 	messageComponents := helper.CreateAddRemoveUpdateButtons(userId, id, foodLog.FoodItem)
 
-	log.Printf("Added food log %v for user %v and retrieved remaining calories.", id, userDisplayName)
-	helper.DisplayFoodLogEmbed(s, i, userId, userDisplayName, time.Now(), messageComponents, true)
+	logger.Info("Added food log", "log_id", id)
+	helper.DisplayFoodLogEmbed(c, userId, userDisplayName, time.Now(), messageComponents, true)
 }
@@ -0,0 +1,96 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/units"
+)
+
+// Discord shows at most 25 suggestions, each named with at most 100 characters
+const (
+	maxChoices          = 25
+	maxChoiceNameLength = 100
+)
+
+// HandleLogIDAutocomplete suggests the users most recent entries for a logid option, matching what they have typed
+// against the entry name or its ID.
+func HandleLogIDAutocomplete(c *discord.Context) {
+	logger := logging.FromContext(c)
+
+	foodLogs, fetchErr := c.Store.SearchRecentFoodLogs(c, c.User.ID, focusedValue(c), maxChoices)
+	if fetchErr != nil {
+		logger.Error("Error searching recent food logs", "error", fetchErr)
+		c.Respond(discord.CreateAutocompleteResponse(nil))
+		return
+	}
+
+	c.Respond(discord.CreateAutocompleteResponse(foodLogChoices(foodLogs, c.Account.EnergyUnit)))
+}
+
+// HandleTrashAutocomplete suggests the entries in the users trash for /trash restore.
+func HandleTrashAutocomplete(c *discord.Context) {
+	logger := logging.FromContext(c)
+
+	foodLogs, fetchErr := c.Store.FetchTrashedFoodLogs(c, c.User.ID)
+	if fetchErr != nil {
+		logger.Error("Error fetching trashed food logs", "error", fetchErr)
+		c.Respond(discord.CreateAutocompleteResponse(nil))
+		return
+	}
+
+	typed := strings.ToLower(focusedValue(c))
+	var matching []database.FoodLog
+	for _, foodLog := range foodLogs {
+		if strings.Contains(strings.ToLower(foodLog.FoodItem), typed) || strings.HasPrefix(strconv.FormatInt(foodLog.ID, 10), typed) {
+			matching = append(matching, foodLog)
+		}
+	}
+
+	c.Respond(discord.CreateAutocompleteResponse(foodLogChoices(matching, c.Account.EnergyUnit)))
+}
+
+// focusedValue is what the user has typed so far in the option being autocompleted, which can be in a subcommand.
+func focusedValue(c *discord.Context) string {
+	options := c.Interaction.ApplicationCommandData().Options
+	for len(options) > 0 {
+		var nested []*discordgo.ApplicationCommandInteractionDataOption
+		for _, option := range options {
+			if option.Focused {
+				// Discord sends what has been typed as a string, even for integer options
+				return strings.TrimSpace(fmt.Sprint(option.Value))
+			}
+			nested = append(nested, option.Options...)
+		}
+		options = nested
+	}
+	return ""
+}
+
+func foodLogChoices(foodLogs []database.FoodLog, energyUnit string) []*discordgo.ApplicationCommandOptionChoice {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, foodLog := range foodLogs {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: foodLogChoiceName(foodLog, energyUnit), Value: foodLog.ID})
+	}
+	return choices
+}
+
+// foodLogChoiceName describes an entry the way it is suggested, e.g 13:05 · Mince Pie · 250. Entries from before today
+// lead with their date.
+func foodLogChoiceName(foodLog database.FoodLog, energyUnit string) string {
+	logged := foodLog.DateTime.Format("15:04")
+	if day := foodLog.DateTime.Format(helper.DATEFORMAT); day != time.Now().Format(helper.DATEFORMAT) {
+		logged = day + " " + logged
+	}
+
+	energy := math.Round(units.FromKcal(helper.FoodLogCalories(foodLog), energyUnit))
+	return discord.Truncate(fmt.Sprintf("%s · %s · %.0f", logged, helper.FoodLogName(foodLog), energy), maxChoiceNameLength)
+}
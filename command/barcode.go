@@ -0,0 +1,63 @@
+package command
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/units"
+)
+
+func HandleBarcodeCommand(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+	user := c.Account
+
+	code := c.Options["code"].StringValue()
+
+	logger.Debug("Looking up barcode", "barcode", code)
+	nutrition, lookupErr := c.Store.FetchNutritionByBarcode(c, code)
+	if lookupErr != nil {
+		logger.Error("Error looking up barcode", "barcode", code, "error", lookupErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	if nutrition.ID == 0 {
+		logger.Info("No product found with barcode", "barcode", code)
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("No product found with barcode %v.", code), true, nil))
+		return
+	}
+
+	grams := nutrition.ServingGrams
+	if gramsOpt, ok := c.Options["grams"]; ok {
+		grams = gramsOpt.FloatValue()
+	}
+	if grams == 0 {
+		grams = 100
+	}
+
+	_, totalCalories := helper.ConvertCalories(100, nutrition.KcalPer100g, grams)
+
+	foodLog := database.FoodLog{
+		UserID:   userId,
+		FoodItem: helper.WeighedFoodItem(nutrition.Name, fmt.Sprintf("%gg", grams)),
+		Quantity: 1,
+	}
+	foodLog = withCalories(foodLog, math.Ceil(totalCalories), 1)
+
+	messageComponents := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				helper.CreateQuickLogButton(userId, &foodLog, true),
+			},
+		},
+	}
+
+	logger.Info("Found barcode", "barcode", code)
+	c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("%v\n%v per 100g \nTotal for %gg is %v", nutrition.Name, units.FormatEnergy(nutrition.KcalPer100g, user.EnergyUnit), grams, units.FormatEnergy(math.Ceil(totalCalories), user.EnergyUnit)), true, messageComponents))
+}
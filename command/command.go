@@ -1,15 +1,95 @@
 package command
 
-import "github.com/bwmarrin/discordgo"
+import (
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/config"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/units"
+)
 
 var (
 	minCalorieIntake = 1.0
 	maxItemCalories  = 5000.0
+	// Energy options accept kJ too, the range is checked in kcal once converted
+	maxItemEnergy = maxItemCalories * units.KilojoulesPerKcal
 
 	minAverageDays = 2.0
-	minQuantity    = 1.0
+	maxAverageDays = 7.0
+	// Quantities can be fractions of a serving, stored to two decimal places
+	minQuantity = 0.01
+	maxQuantity = 1000.0
+
+	maxServingLength = 20
+
+	// Conversions divide by the label amount, so it and the weight can't be 0
+	minConvAmount = 0.01
+
+	minBarcodeLength = 8
+	minGrams         = 1.0
+
+	unitChoices = []*discordgo.ApplicationCommandOptionChoice{
+		{Name: "g", Value: "g"},
+		{Name: "kg", Value: "kg"},
+		{Name: "oz", Value: "oz"},
+		{Name: "lb", Value: "lb"},
+		{Name: "ml", Value: "ml"},
+		{Name: "l", Value: "l"},
+		{Name: "cup", Value: "cup"},
+		{Name: "tbsp", Value: "tbsp"},
+		{Name: "tsp", Value: "tsp"},
+		{Name: "fl oz", Value: "floz"},
+	}
+
+	allContexts = []discordgo.InteractionContextType{
+		discordgo.InteractionContextGuild,
+		discordgo.InteractionContextBotDM,
+		discordgo.InteractionContextPrivateChannel,
+	}
+	allIntegrationTypes = []discordgo.ApplicationIntegrationType{
+		discordgo.ApplicationIntegrationGuildInstall,
+		discordgo.ApplicationIntegrationUserInstall,
+	}
 
-	CommandDefinitions = []*discordgo.ApplicationCommand{
+	CommandDefinitions = commandDefinitions()
+
+	CommandHandlers = map[string]discord.Handler{
+		"set":     HandleSetCommand,
+		"add":     discord.Chain(HandleAddCommand, discord.RequireDailyCalories, discord.LimitDailyEntries),
+		"update":  HandleUpdateCommand,
+		"del":     HandleDeleteCommand,
+		"conv":    HandleConvCommand,
+		"list":    HandleListCommand,
+		"avg":     HandleAverageCommand,
+		"log":     discord.Chain(HandleLogCommand, discord.RequireDailyCalories),
+		"food":    HandleFoodCommand,
+		"barcode": HandleBarcodeCommand,
+		"units":   HandleUnitsCommand,
+		"undo":    HandleUndoCommand,
+		"history": HandleHistoryCommand,
+		"trash":   HandleTrashCommand,
+	}
+
+	// AutocompleteHandlers suggest values for command options marked Autocomplete, by command name
+	AutocompleteHandlers = map[string]discord.Handler{
+		"update":  HandleLogIDAutocomplete,
+		"del":     HandleLogIDAutocomplete,
+		"history": HandleLogIDAutocomplete,
+		"trash":   HandleTrashAutocomplete,
+	}
+)
+
+// Configure applies the configured limits and rebuilds the command definitions to match.
+func Configure(limits config.Limits) {
+	maxItemCalories = limits.MaxItemCalories
+	maxItemEnergy = maxItemCalories * units.KilojoulesPerKcal
+	maxAverageDays = limits.MaxAverageDays
+
+	CommandDefinitions = commandDefinitions()
+}
+
+func commandDefinitions() []*discordgo.ApplicationCommand {
+	definitions := []*discordgo.ApplicationCommand{
 		{
 			Name:        "set",
 			Description: "Set your daily calorie intake",
@@ -17,10 +97,10 @@ var (
 				{
 					Type:        discordgo.ApplicationCommandOptionInteger,
 					Name:        "calories",
-					Description: "The amount of calories you need to intake daily",
+					Description: "The amount of calories you need to intake daily, in kJ if that is your energy unit",
 					Required:    true,
 					MinValue:    &minCalorieIntake,
-					MaxValue:    maxItemCalories,
+					MaxValue:    maxItemEnergy,
 				},
 			},
 		},
@@ -33,41 +113,51 @@ var (
 					Name:        "days",
 					Description: "The amount of days to calculate the average for",
 					MinValue:    &minAverageDays,
-					MaxValue:    7,
+					MaxValue:    maxAverageDays,
 					Required:    true,
 				},
 			},
 		},
 		{
 			Name:        "update",
-			Description: "Update a log entry",
+			Description: "Update a log entry, only the options given are changed",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "logid",
-					Description: "The ID of the log entry",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionInteger,
+					Name:         "logid",
+					Description:  "The ID of the log entry, start typing to pick one of your recent entries",
+					Required:     true,
+					Autocomplete: true,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
 					Name:        "fooditem",
-					Description: "The name of the food product",
-					Required:    true,
+					Description: "The new name of the food product",
+					Required:    false,
+					MaxLength:   helper.MaxFoodItemLength,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionInteger,
 					Name:        "calories",
-					Description: "Calories consumed by eating this product",
-					Required:    true,
+					Description: "The new calories for a serving of this product, in kJ if that is your energy unit",
+					Required:    false,
 					MinValue:    &minCalorieIntake,
-					MaxValue:    maxItemCalories,
+					MaxValue:    maxItemEnergy,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
+					Type:        discordgo.ApplicationCommandOptionNumber,
 					Name:        "quantity",
-					Description: "The quantity consumed",
+					Description: "The new number of servings consumed, e.g 1.5",
 					Required:    false,
 					MinValue:    &minQuantity,
+					MaxValue:    maxQuantity,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "serving",
+					Description: "The new serving size the calories are for, e.g slice or 100 g, or none to remove it",
+					Required:    false,
+					MaxLength:   maxServingLength,
 				},
 			},
 		},
@@ -94,10 +184,58 @@ var (
 			Description: "Delete a log entry",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "logid",
-					Description: "The ID of the log entry",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionInteger,
+					Name:         "logid",
+					Description:  "The ID of the log entry, start typing to pick one of your recent entries",
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        "undo",
+			Description: "Undo your latest change to your log",
+		},
+		{
+			Name:        "history",
+			Description: "Show every change made to a log entry",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionInteger,
+					Name:         "logid",
+					Description:  "The ID of the log entry, start typing to pick one of your recent entries",
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        "trash",
+			Description: "See, restore or permanently delete the log entries you have deleted",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "list",
+					Description: "List the entries in your trash",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "restore",
+					Description: "Put a deleted entry back in your log",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:         discordgo.ApplicationCommandOptionInteger,
+							Name:         "logid",
+							Description:  "The ID of the log entry, start typing to pick one of the entries in your trash",
+							Required:     true,
+							Autocomplete: true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "empty",
+					Description: "Permanently delete every entry in your trash",
 				},
 			},
 		},
@@ -110,22 +248,30 @@ var (
 					Name:        "fooditem",
 					Description: "The name of the food product",
 					Required:    true,
-					MaxLength:   50,
+					MaxLength:   helper.MaxFoodItemLength,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionInteger,
 					Name:        "calories",
-					Description: "The amount of calories consumed by eating this product",
+					Description: "Calories consumed by eating this product, in kJ if that is your energy unit",
 					Required:    true,
 					MinValue:    &minCalorieIntake,
-					MaxValue:    maxItemCalories,
+					MaxValue:    maxItemEnergy,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
+					Type:        discordgo.ApplicationCommandOptionNumber,
 					Name:        "quantity",
-					Description: "The quantity consumed",
+					Description: "The number of servings consumed, e.g 0.5 for half",
 					Required:    false,
 					MinValue:    &minQuantity,
+					MaxValue:    maxQuantity,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "serving",
+					Description: "The serving size the calories are for, e.g slice or 100 g",
+					Required:    false,
+					MaxLength:   maxServingLength,
 				},
 			},
 		},
@@ -138,6 +284,7 @@ var (
 					Name:        "units",
 					Description: "The per X units (e.g grams or ml) given on the nutrition label",
 					Required:    true,
+					MinValue:    &minConvAmount,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionNumber,
@@ -145,31 +292,130 @@ var (
 					Description: "The amount of calories for the unit specified",
 					Required:    true,
 					MinValue:    &minCalorieIntake,
-					MaxValue:    maxItemCalories,
+					MaxValue:    maxItemEnergy,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionNumber,
 					Name:        "weight",
 					Description: "The actual weight in units of the packet",
 					Required:    true,
+					MinValue:    &minConvAmount,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
 					Name:        "fooditem",
-					Description: "The name of the food product",
+					Description: "The name of the food product, needed to add it to your log",
+					Required:    false,
+					MaxLength:   helper.MaxFoodItemLength,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "labelunit",
+					Description: "The unit used on the nutrition label",
+					Required:    false,
+					Choices:     unitChoices,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "weightunit",
+					Description: "The unit of the packet weight",
+					Required:    false,
+					Choices:     unitChoices,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "energy",
+					Description: "Whether the label gives kcal or kJ, defaults to kcal",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "kcal", Value: units.Kcal},
+						{Name: "kJ", Value: units.Kilojoules},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "log",
+					Description: "Add the result straight to your log, needs the food item name",
 					Required:    false,
 				},
 			},
 		},
+		{
+			Name:        "log",
+			Description: "Quickly log a meal described in plain text, e.g 2 slices toast and a banana",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "text",
+					Description: "What you ate",
+					Required:    true,
+					MaxLength:   200,
+				},
+			},
+		},
+		{
+			Name:        "food",
+			Description: "Look up foods in the nutrition database",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "search",
+					Description: "Search foods by name and log one in a click",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "query",
+							Description: "The food to search for",
+							Required:    true,
+							MaxLength:   100,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:        "barcode",
+			Description: "Look up a product by its barcode and work out the calories for your portion",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "code",
+					Description: "The EAN or UPC barcode number on the packet",
+					Required:    true,
+					MinLength:   &minBarcodeLength,
+					MaxLength:   14,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionNumber,
+					Name:        "grams",
+					Description: "How many grams you ate, defaults to the serving size",
+					Required:    false,
+					MinValue:    &minGrams,
+				},
+			},
+		},
+		{
+			Name:        "units",
+			Description: "Choose whether energy is shown and entered in kcal or kJ",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "energy",
+					Description: "The energy unit to use",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "kcal", Value: units.Kcal},
+						{Name: "kJ", Value: units.Kilojoules},
+					},
+				},
+			},
+		},
 	}
 
-	CommandHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
-		"set":    HandleSetCommand,
-		"add":    HandleAddCommand,
-		"update": HandleUpdateCommand,
-		"del":    HandleDeleteCommand,
-		"conv":   HandleConvCommand,
-		"list":   HandleListCommand,
-		"avg":    HandleAverageCommand,
+	// Every command works in servers, DMs with the bot and, when installed to a user, any other DM
+	for _, definition := range definitions {
+		definition.Contexts = &allContexts
+		definition.IntegrationTypes = &allIntegrationTypes
 	}
-)
+	return definitions
+}
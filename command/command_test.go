@@ -0,0 +1,696 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/discord/discordtest"
+	"github.com/discordcalorietracker/helper"
+)
+
+var (
+	ctx = context.Background()
+
+	alice = &discordgo.User{ID: "100", GlobalName: "Alice"}
+	bob   = &discordgo.User{ID: "200", GlobalName: "Bob"}
+	robot = &discordgo.User{ID: "300", GlobalName: "Robot", Bot: true}
+
+	option  = discordtest.Option
+	command = discordtest.Command
+)
+
+type setupFunc func(t *testing.T, store database.Store)
+
+type checkFunc func(t *testing.T, resp *discordgo.InteractionResponse, store database.Store)
+
+func TestCommands(t *testing.T) {
+	today := time.Now().Format(helper.DATEFORMAT)
+
+	tests := []struct {
+		name        string
+		setup       []setupFunc
+		interaction *discordgo.InteractionCreate
+		checks      []checkFunc
+	}{
+		{
+			name:        "set stores the daily calories",
+			interaction: command(alice, "set", option("calories", 2000)),
+			checks: []checkFunc{
+				wantMessage("Your daily intake has successfully been set to 2000 calories."),
+				wantUser(alice.ID, 2000, "kcal"),
+			},
+		},
+		{
+			name:        "set converts kJ to kcal",
+			setup:       []setupFunc{withUser(alice, 2000, "kj")},
+			interaction: command(alice, "set", option("calories", 8368)),
+			checks: []checkFunc{
+				wantMessage("Your daily intake has successfully been set to 8368 kJ."),
+				wantUser(alice.ID, 2000, "kj"),
+			},
+		},
+		{
+			name:        "set rejects values over the limit",
+			interaction: command(alice, "set", option("calories", 6000)),
+			checks: []checkFunc{
+				wantMessage("The value must be between 1 calories and 5000 calories."),
+				wantUser(alice.ID, 0, "kcal"),
+			},
+		},
+		{
+			name:        "units works before set",
+			interaction: command(alice, "units", option("energy", "kj")),
+			checks: []checkFunc{
+				wantMessage("Energy will now be shown and entered in kJ."),
+				wantUser(alice.ID, 0, "kj"),
+			},
+		},
+		{
+			name:        "units changes the energy unit",
+			setup:       []setupFunc{withUser(alice, 2000, "")},
+			interaction: command(alice, "units", option("energy", "kj")),
+			checks: []checkFunc{
+				wantMessage("Energy will now be shown and entered in kJ."),
+				wantUser(alice.ID, 2000, "kj"),
+			},
+		},
+		{
+			name:        "add needs set first",
+			interaction: command(alice, "add", option("fooditem", "Toast"), option("calories", 250)),
+			checks:      []checkFunc{wantMessage("Set your daily calories first using the /set command.")},
+		},
+		{
+			name:        "add shows the days log",
+			setup:       []setupFunc{withUser(alice, 2000, "")},
+			interaction: command(alice, "add", option("fooditem", "Toast"), option("calories", 250), option("quantity", 2.0)),
+			checks: []checkFunc{
+				wantEmbed(true,
+					"Food Log - Alice ("+today+")",
+					"**Daily Calories**: 2000",
+					"(1) x2 Toast",
+					"**Total Consumed**: 500",
+					"**Remaining On Day**: 1500",
+				),
+				wantButtons("flquantity_inc_100_1_Toast", "flquantity_dec_100_1_Toast", "fledit_100_1", "fldel_100_1_Toast", "undo_100_1"),
+				wantSavedFood(alice.ID, "toast", 250),
+			},
+		},
+		{
+			name:        "add works in DMs with the bot",
+			setup:       []setupFunc{withUser(alice, 2000, "")},
+			interaction: discordtest.DirectMessage(command(alice, "add", option("fooditem", "Toast"), option("calories", 250)), discordgo.InteractionContextBotDM),
+			checks: []checkFunc{
+				wantEmbed(true, "Food Log - Alice ("+today+")", "(1) Toast"),
+				wantConsumed(alice.ID, 250),
+			},
+		},
+		{
+			name:        "add shows kJ users their entries in kJ",
+			setup:       []setupFunc{withUser(alice, 2000, "kj")},
+			interaction: command(alice, "add", option("fooditem", "Toast"), option("calories", 1046)),
+			checks: []checkFunc{
+				wantEmbed(true, "**Daily kJ**: 8368", "**Total Consumed**: 1046"),
+			},
+		},
+		{
+			name:        "update replaces the entry",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1)},
+			interaction: command(alice, "update", option("logid", 1), option("fooditem", "Brown Toast"), option("calories", 300)),
+			checks: []checkFunc{
+				wantEmbed(true, "(1) Brown Toast", "**Total Consumed**: 300"),
+				wantButtons("flquantity_inc_100_1_Brown Toast", "flquantity_dec_100_1_Brown Toast", "fledit_100_1", "fldel_100_1_Brown Toast", "undo_100_1"),
+			},
+		},
+		{
+			name:        "update only changes the options given",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 2)},
+			interaction: command(alice, "update", option("logid", 1), option("calories", 100)),
+			checks: []checkFunc{
+				wantEmbed(true, "(1) x2 Toast", "**Total Consumed**: 200"),
+				wantConsumed(alice.ID, 200),
+			},
+		},
+		{
+			name:        "add takes fractions of a serving",
+			setup:       []setupFunc{withUser(alice, 2000, "")},
+			interaction: command(alice, "add", option("fooditem", "Pizza"), option("calories", 285), option("quantity", 1.5), option("serving", "slice")),
+			checks: []checkFunc{
+				wantEmbed(true, "(1) x1.5 Pizza (slice)", "**Total Consumed**: 428"),
+				wantConsumed(alice.ID, 428),
+			},
+		},
+		{
+			name:        "add rejects quantities with too many decimal places to store",
+			setup:       []setupFunc{withUser(alice, 2000, "")},
+			interaction: command(alice, "add", option("fooditem", "Pizza"), option("calories", 285), option("quantity", 0.001)),
+			checks: []checkFunc{
+				wantMessage("The quantity must be a number between 0.01 and 1000."),
+			},
+		},
+		{
+			name:        "update changes the quantity and serving",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Rice", 130, 1)},
+			interaction: command(alice, "update", option("logid", 1), option("quantity", 2.5), option("serving", "100 g")),
+			checks: []checkFunc{
+				wantEmbed(true, "(1) x2.5 Rice (100 g)", "**Total Consumed**: 325"),
+				wantConsumed(alice.ID, 325),
+			},
+		},
+		{
+			name:        "update needs something to change",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 2)},
+			interaction: command(alice, "update", option("logid", 1)),
+			checks: []checkFunc{
+				wantMessage("Provide a new food item, calories, quantity or serving to update the entry."),
+				wantConsumed(alice.ID, 500),
+			},
+		},
+		{
+			name:        "logid suggests the most recent entries first",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 2), withLog(alice, "Banana", 105, 1), withLog(bob, "Toast", 80, 1)},
+			interaction: discordtest.Autocomplete(alice, "update", discordtest.Focused("logid", "", discordgo.ApplicationCommandOptionInteger)),
+			checks:      []checkFunc{wantChoices(" · Banana · 105", " · x2 Toast · 500")},
+		},
+		{
+			name:        "logid suggestions match what has been typed",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1), withLog(alice, "Banana", 105, 1)},
+			interaction: discordtest.Autocomplete(alice, "del", discordtest.Focused("logid", "ban", discordgo.ApplicationCommandOptionInteger)),
+			checks:      []checkFunc{wantChoices(" · Banana · 105")},
+		},
+		{
+			name:        "logid suggestions are in the users energy unit",
+			setup:       []setupFunc{withUser(alice, 2000, "kj"), withLog(alice, "Toast", 100, 1)},
+			interaction: discordtest.Autocomplete(alice, "history", discordtest.Focused("logid", "1", discordgo.ApplicationCommandOptionInteger)),
+			checks:      []checkFunc{wantChoices(" · Toast · 418")},
+		},
+		{
+			name:        "trash restore suggests the entries in the trash",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1), withLog(alice, "Banana", 105, 1), withDeletedLog(alice, 1)},
+			interaction: discordtest.Autocomplete(alice, "trash", discordtest.Subcommand("restore", discordtest.Focused("logid", "", discordgo.ApplicationCommandOptionInteger))),
+			checks:      []checkFunc{wantChoices(" · Toast · 250")},
+		},
+		{
+			name:        "update only changes the users own entries",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withUser(bob, 2000, ""), withLog(alice, "Toast", 250, 1)},
+			interaction: command(bob, "update", option("logid", 1), option("fooditem", "Stolen"), option("calories", 1)),
+			checks: []checkFunc{
+				wantMessage("Could not find a food log with ID 1."),
+				wantConsumed(alice.ID, 250),
+			},
+		},
+		{
+			name:        "del removes the entry",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1), withLog(alice, "Banana", 105, 1)},
+			interaction: command(alice, "del", option("logid", 1)),
+			checks: []checkFunc{
+				wantEmbed(true, "(2) Banana", "**Total Consumed**: 105"),
+				wantButtons("undo_100_1"),
+				wantConsumed(alice.ID, 105),
+			},
+		},
+		{
+			name:        "del of the last entry leaves no logs",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1)},
+			interaction: command(alice, "del", option("logid", 1)),
+			checks:      []checkFunc{wantMessage("No logs found for Alice on " + today + ".")},
+		},
+		{
+			name:        "del of an unknown entry",
+			setup:       []setupFunc{withUser(alice, 2000, "")},
+			interaction: command(alice, "del", option("logid", 99)),
+			checks:      []checkFunc{wantMessage("Could not find a food log with ID 99.")},
+		},
+		{
+			name:        "undo reverts the latest change",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1), withLog(alice, "Banana", 105, 1)},
+			interaction: command(alice, "undo"),
+			checks: []checkFunc{
+				wantMessage("Undid a change to entry 2: Added Banana (105 calories)."),
+				wantConsumed(alice.ID, 250),
+			},
+		},
+		{
+			name:        "undo brings back deleted entries",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 2), withDeletedLog(alice, 1)},
+			interaction: command(alice, "undo"),
+			checks: []checkFunc{
+				wantMessage("Undid a change to entry 1: Deleted x2 Toast (250 calories each)."),
+				wantButtons("flquantity_inc_100_1_Toast", "flquantity_dec_100_1_Toast", "fledit_100_1", "fldel_100_1_Toast", "undo_100_1"),
+				wantConsumed(alice.ID, 500),
+			},
+		},
+		{
+			name:        "undo with nothing to undo",
+			setup:       []setupFunc{withUser(alice, 2000, "")},
+			interaction: command(alice, "undo"),
+			checks:      []checkFunc{wantMessage("There is nothing left to undo.")},
+		},
+		{
+			name:        "history shows every change to the entry",
+			setup:       []setupFunc{withUser(alice, 2000, "kj"), withLog(alice, "Toast", 250, 2), withDeletedLog(alice, 1)},
+			interaction: command(alice, "history", option("logid", 1)),
+			checks: []checkFunc{
+				wantEmbed(true, "History of entry 1", "Added x2 Toast (1046 kJ each)", "Deleted x2 Toast"),
+			},
+		},
+		{
+			name:        "history only shows the users own entries",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1)},
+			interaction: command(bob, "history", option("logid", 1)),
+			checks:      []checkFunc{wantMessage("Could not find a food log with ID 1.")},
+		},
+		{
+			name:        "trash lists deleted entries",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 2), withDeletedLog(alice, 1)},
+			interaction: command(alice, "trash", discordtest.Subcommand("list")),
+			checks:      []checkFunc{wantMessageContaining("(1) x2 Toast, 500 calories, deleted <t:")},
+		},
+		{
+			name:        "trash restore puts the entry back",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1), withDeletedLog(alice, 1)},
+			interaction: command(alice, "trash", discordtest.Subcommand("restore", option("logid", 1))),
+			checks: []checkFunc{
+				wantEmbed(true, "(1) Toast", "**Total Consumed**: 250"),
+				wantConsumed(alice.ID, 250),
+			},
+		},
+		{
+			name:        "trash restore only finds deleted entries",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1)},
+			interaction: command(alice, "trash", discordtest.Subcommand("restore", option("logid", 1))),
+			checks:      []checkFunc{wantMessage("Could not find a food log with ID 1 in your trash.")},
+		},
+		{
+			name:        "trash empty deletes entries for good",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1), withDeletedLog(alice, 1)},
+			interaction: command(alice, "trash", discordtest.Subcommand("empty")),
+			checks:      []checkFunc{wantMessage("Permanently deleted 1 entries from your trash.")},
+		},
+		{
+			name:        "list shows the log publicly with an update button",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1)},
+			interaction: command(alice, "list"),
+			checks: []checkFunc{
+				wantEmbed(false, "Food Log - Alice ("+today+")", "(1) Toast"),
+				wantButtons("fllist_100_Alice_" + today),
+			},
+		},
+		{
+			name:        "list works in other DMs when installed to the user",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1)},
+			interaction: discordtest.DirectMessage(command(alice, "list"), discordgo.InteractionContextPrivateChannel),
+			checks:      []checkFunc{wantEmbed(false, "Food Log - Alice ("+today+")", "(1) Toast")},
+		},
+		{
+			name:        "list shows another users log",
+			setup:       []setupFunc{withUser(bob, 1800, ""), withLog(bob, "Banana", 105, 1)},
+			interaction: command(alice, "list", option("user", bob)),
+			checks:      []checkFunc{wantEmbed(false, "Food Log - Bob", "**Daily Calories**: 1800", "(1) Banana")},
+		},
+		{
+			name:        "list refuses bots",
+			interaction: command(alice, "list", option("user", robot)),
+			checks:      []checkFunc{wantMessage("That is a bot, please select a user.")},
+		},
+		{
+			name:        "list rejects badly formatted dates",
+			interaction: command(alice, "list", option("date", "2023-12-31")),
+			checks:      []checkFunc{wantMessage("Error parsing date, please try again with format like " + today + ".")},
+		},
+		{
+			name:        "avg needs enough days of logs",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1)},
+			interaction: command(alice, "avg", option("days", 2)),
+			checks:      []checkFunc{wantMessage("You only have enough data to request an average over 1 days.")},
+		},
+		{
+			name:        "avg averages the logged days",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 2)},
+			interaction: command(alice, "avg", option("days", 1)),
+			checks:      []checkFunc{wantMessage("You have consumed an average of 500 calories over 1 days.")},
+		},
+		{
+			name:        "log needs set first",
+			interaction: command(alice, "log", option("text", "a banana")),
+			checks:      []checkFunc{wantMessage("Set your daily calories first using the /set command.")},
+		},
+		{
+			name:        "log proposes entries from saved foods and nutrition data",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withSavedFood(alice, "Porridge", 300)},
+			interaction: command(alice, "log", option("text", "porridge and a banana")),
+			checks: []checkFunc{
+				wantMessage("✅ porridge - 300 calories\n✅ banana - 105 calories\n\nPress a button to add the entry to your log."),
+				wantButtons("qlog_100_300_1_s__porridge", "qlog_100_105_1_s__banana"),
+			},
+		},
+		{
+			name:        "log keeps the serving size",
+			setup:       []setupFunc{withUser(alice, 2000, ""), withSavedFood(alice, "Toast", 80)},
+			interaction: command(alice, "log", option("text", "2 x slices of toast")),
+			checks: []checkFunc{
+				wantMessage("✅ x2 toast (slice) - 160 calories\n\nPress a button to add the entry to your log."),
+				wantButtons("qlog_100_80_2_s_slice_toast"),
+			},
+		},
+		{
+			name:        "log reports foods it can't find",
+			setup:       []setupFunc{withUser(alice, 2000, "")},
+			interaction: command(alice, "log", option("text", "a glorp")),
+			checks:      []checkFunc{wantMessage("❔ glorp - no match found, use /add instead\n")},
+		},
+		{
+			name:        "food search lists matching foods",
+			interaction: command(alice, "food", discordtest.Subcommand("search", option("query", "banan"))),
+			checks: []checkFunc{
+				wantMessage("**1. banana** - 89 calories per 100g, serving 118g is 105 calories\n"),
+				wantButtons("qlog_100_105_1_w__banana (118g)"),
+			},
+		},
+		{
+			name:        "food search with no matches",
+			interaction: command(alice, "food", discordtest.Subcommand("search", option("query", "glorp"))),
+			checks:      []checkFunc{wantMessage(`No foods found matching "glorp".`)},
+		},
+		{
+			name:        "barcode finds imported products",
+			setup:       []setupFunc{withProduct("Digestive Biscuits", 480, 15, "5000168001142")},
+			interaction: command(alice, "barcode", option("code", "5000168001142"), option("grams", 30.0)),
+			checks: []checkFunc{
+				wantMessage("Digestive Biscuits\n480 calories per 100g \nTotal for 30g is 144 calories"),
+				wantButtons("qlog_100_144_1_w__Digestive Biscuits (30g)"),
+			},
+		},
+		{
+			name:        "barcode with an unknown product",
+			interaction: command(alice, "barcode", option("code", "1234567890123")),
+			checks:      []checkFunc{wantMessage("No product found with barcode 1234567890123.")},
+		},
+		{
+			name:        "conv works out the calories eaten",
+			interaction: command(alice, "conv", option("units", 100.0), option("calories", 450.0), option("weight", 30.0)),
+			checks: []checkFunc{
+				wantMessage("4.50 calories per unit \nTotal amount of calories is 135"),
+				wantPublic,
+			},
+		},
+		{
+			name:        "conv rejects a label amount of 0",
+			setup:       []setupFunc{withUser(alice, 2000, "")},
+			interaction: command(alice, "conv", option("units", 0.0), option("calories", 450.0), option("weight", 30.0), option("fooditem", "Digestive"), option("log", true)),
+			checks:      []checkFunc{wantMessage("The units and weight must be at least 0.01.")},
+		},
+		{
+			name:        "conv offers to log named foods",
+			interaction: command(alice, "conv", option("units", 100.0), option("calories", 450.0), option("weight", 30.0), option("fooditem", "Digestive")),
+			checks: []checkFunc{
+				wantMessage("Digestive\n4.50 calories per unit \nTotal amount of calories is 135"),
+				wantButtons("convlog_100_135_4.5000_Digestive"),
+			},
+		},
+		{
+			name:        "conv between units of weight",
+			interaction: command(alice, "conv", option("units", 100.0), option("calories", 1880.0), option("weight", 1.0), option("labelunit", "g"), option("weightunit", "kg"), option("energy", "kj")),
+			checks:      []checkFunc{wantMessage("4.49 calories per g \nTotal amount of calories is 4494")},
+		},
+		{
+			name:        "conv between weight and volume needs a known food",
+			interaction: command(alice, "conv", option("units", 100.0), option("calories", 389.0), option("weight", 1.0), option("labelunit", "g"), option("weightunit", "cup")),
+			checks:      []checkFunc{wantMessage("Can't convert cup to g without knowing the density of the food, provide a known fooditem like milk or flour or use the same kind of unit on both sides.")},
+		},
+		{
+			name:        "conv logs straight away when asked",
+			setup:       []setupFunc{withUser(alice, 2000, "")},
+			interaction: command(alice, "conv", option("units", 100.0), option("calories", 450.0), option("weight", 30.0), option("fooditem", "Digestive"), option("log", true)),
+			checks: []checkFunc{
+				wantEmbed(true, "(1) Digestive", "**Total Consumed**: 135"),
+				wantConsumed(alice.ID, 135),
+			},
+		},
+		{
+			name:        "conv needs a name to log",
+			interaction: command(alice, "conv", option("units", 100.0), option("calories", 450.0), option("weight", 30.0), option("log", true)),
+			checks:      []checkFunc{wantMessage("Provide the fooditem name to add the conversion to your log.")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := discordtest.NewStore(t)
+			for _, setup := range test.setup {
+				setup(t, store)
+			}
+
+			responder := &discordtest.Responder{Users: map[string]*discordgo.User{bob.ID: bob, robot.ID: robot}}
+			handlers := CommandHandlers
+			if test.interaction.Type == discordgo.InteractionApplicationCommandAutocomplete {
+				handlers = AutocompleteHandlers
+			}
+			handler, ok := handlers[test.interaction.ApplicationCommandData().Name]
+			if !ok {
+				t.Fatalf("no handler for /%v", test.interaction.ApplicationCommandData().Name)
+			}
+			discord.Wrap(handler)(discord.NewContext(ctx, responder, test.interaction, store))
+
+			if len(responder.Responses) != 1 {
+				t.Fatalf("got %d responses, want 1", len(responder.Responses))
+			}
+			for _, check := range test.checks {
+				check(t, responder.Last(t), store)
+			}
+		})
+	}
+}
+
+// emptiedTrashStore empties the trash just before a restore, as if another interaction got there first.
+type emptiedTrashStore struct {
+	database.Store
+}
+
+func (s emptiedTrashStore) RestoreFoodLog(ctx context.Context, userId string, logId int64) (int64, error) {
+	if _, err := s.Store.EmptyTrash(ctx, userId); err != nil {
+		return 0, err
+	}
+	return s.Store.RestoreFoodLog(ctx, userId, logId)
+}
+
+func TestTrashRestoreAfterTheTrashIsEmptied(t *testing.T) {
+	store := discordtest.NewStore(t)
+	for _, setup := range []setupFunc{withUser(alice, 2000, ""), withLog(alice, "Toast", 250, 1), withDeletedLog(alice, 1)} {
+		setup(t, store)
+	}
+
+	responder := &discordtest.Responder{}
+	interaction := command(alice, "trash", discordtest.Subcommand("restore", option("logid", 1)))
+	discord.Wrap(CommandHandlers["trash"])(discord.NewContext(ctx, responder, interaction, emptiedTrashStore{store}))
+
+	wantMessage("The food log with ID 1 is no longer in your trash.")(t, responder.Last(t), store)
+}
+
+func TestEveryCommandHasAHandler(t *testing.T) {
+	for _, definition := range CommandDefinitions {
+		if _, ok := CommandHandlers[definition.Name]; !ok {
+			t.Errorf("/%v has no handler", definition.Name)
+		}
+	}
+	if len(CommandHandlers) != len(CommandDefinitions) {
+		t.Errorf("%d handlers for %d commands", len(CommandHandlers), len(CommandDefinitions))
+	}
+}
+
+func TestEveryAutocompleteOptionHasAHandler(t *testing.T) {
+	for _, definition := range CommandDefinitions {
+		options := append([]*discordgo.ApplicationCommandOption{}, definition.Options...)
+		for len(options) > 0 {
+			option := options[0]
+			options = append(options[1:], option.Options...)
+			if _, ok := AutocompleteHandlers[definition.Name]; option.Autocomplete && !ok {
+				t.Errorf("/%v %v autocompletes without a handler", definition.Name, option.Name)
+			}
+		}
+	}
+}
+
+func TestEveryCommandWorksOutsideServers(t *testing.T) {
+	for _, definition := range CommandDefinitions {
+		if definition.Contexts == nil || len(*definition.Contexts) != 3 {
+			t.Errorf("/%v contexts = %v, want servers, bot DMs and other DMs", definition.Name, definition.Contexts)
+		}
+		if definition.IntegrationTypes == nil || len(*definition.IntegrationTypes) != 2 {
+			t.Errorf("/%v integration types = %v, want server and user installs", definition.Name, definition.IntegrationTypes)
+		}
+	}
+}
+
+func withUser(user *discordgo.User, dailyCalories int16, energyUnit string) setupFunc {
+	return func(t *testing.T, store database.Store) {
+		t.Helper()
+		if err := store.SetUserCalories(ctx, &database.User{ID: user.ID, DailyCalories: dailyCalories}); err != nil {
+			t.Fatalf("setting user: %v", err)
+		}
+		if energyUnit != "" {
+			if _, err := store.SetUserEnergyUnit(ctx, user.ID, energyUnit); err != nil {
+				t.Fatalf("setting energy unit: %v", err)
+			}
+		}
+	}
+}
+
+func withLog(user *discordgo.User, foodItem string, calories int16, quantity float64) setupFunc {
+	return func(t *testing.T, store database.Store) {
+		t.Helper()
+		if _, err := store.AddUserFoodLog(ctx, &database.FoodLog{UserID: user.ID, FoodItem: foodItem, Calories: calories, Quantity: quantity}); err != nil {
+			t.Fatalf("adding food log: %v", err)
+		}
+	}
+}
+
+func withDeletedLog(user *discordgo.User, logId int64) setupFunc {
+	return func(t *testing.T, store database.Store) {
+		t.Helper()
+		if _, err := store.DeleteUserFoodLog(ctx, user.ID, logId); err != nil {
+			t.Fatalf("deleting food log: %v", err)
+		}
+	}
+}
+
+func withSavedFood(user *discordgo.User, name string, calories int16) setupFunc {
+	return func(t *testing.T, store database.Store) {
+		t.Helper()
+		if err := store.SaveUserFood(ctx, &database.SavedFood{UserID: user.ID, Name: name, Calories: calories}); err != nil {
+			t.Fatalf("saving food: %v", err)
+		}
+	}
+}
+
+func withProduct(name string, kcalPer100g float64, servingGrams float64, barcode string) setupFunc {
+	return func(t *testing.T, store database.Store) {
+		t.Helper()
+		_, err := store.ImportNutrition(ctx, "test", func(add func(database.Nutrition) error) error {
+			return add(database.Nutrition{Name: name, KcalPer100g: kcalPer100g, ServingGrams: servingGrams, Barcode: barcode})
+		})
+		if err != nil {
+			t.Fatalf("importing product: %v", err)
+		}
+	}
+}
+
+// wantMessage checks for a plain ephemeral message with exactly the given text.
+func wantMessage(content string) checkFunc {
+	return func(t *testing.T, resp *discordgo.InteractionResponse, store database.Store) {
+		t.Helper()
+		if got := discordtest.Content(resp); got != content {
+			t.Errorf("content = %q, want %q", got, content)
+		}
+		if len(resp.Data.Embeds) > 0 {
+			t.Errorf("got %d embeds, want none", len(resp.Data.Embeds))
+		}
+	}
+}
+
+// wantMessageContaining checks for a plain message including the given text, for content with timestamps in it.
+func wantMessageContaining(text string) checkFunc {
+	return func(t *testing.T, resp *discordgo.InteractionResponse, store database.Store) {
+		t.Helper()
+		if got := discordtest.Content(resp); !strings.Contains(got, text) {
+			t.Errorf("content = %q, want it to contain %q", got, text)
+		}
+	}
+}
+
+func wantPublic(t *testing.T, resp *discordgo.InteractionResponse, store database.Store) {
+	t.Helper()
+	if discordtest.Ephemeral(resp) {
+		t.Errorf("response is ephemeral, want it visible to everyone")
+	}
+}
+
+// wantEmbed checks for a single food log embed containing each of the given texts.
+func wantEmbed(ephemeral bool, texts ...string) checkFunc {
+	return func(t *testing.T, resp *discordgo.InteractionResponse, store database.Store) {
+		t.Helper()
+		if len(resp.Data.Embeds) != 1 {
+			t.Fatalf("got %d embeds, want 1. Content: %q", len(resp.Data.Embeds), discordtest.Content(resp))
+		}
+		if discordtest.Ephemeral(resp) != ephemeral {
+			t.Errorf("ephemeral = %v, want %v", discordtest.Ephemeral(resp), ephemeral)
+		}
+
+		all := discordtest.EmbedText(resp)
+		for _, text := range texts {
+			if !strings.Contains(all, text) {
+				t.Errorf("embed is missing %q, got:\n%v", text, all)
+			}
+		}
+	}
+}
+
+func wantButtons(customIDs ...string) checkFunc {
+	return func(t *testing.T, resp *discordgo.InteractionResponse, store database.Store) {
+		t.Helper()
+		buttons := discordtest.Buttons(resp)
+		var got []string
+		for _, button := range buttons {
+			got = append(got, button.CustomID)
+		}
+		if strings.Join(got, ",") != strings.Join(customIDs, ",") {
+			t.Errorf("buttons = %q, want %q", got, customIDs)
+		}
+	}
+}
+
+// wantChoices checks the suggestions in order by how they end, as they lead with the time the entry was logged.
+func wantChoices(suffixes ...string) checkFunc {
+	return func(t *testing.T, resp *discordgo.InteractionResponse, store database.Store) {
+		t.Helper()
+		if resp.Type != discordgo.InteractionApplicationCommandAutocompleteResult {
+			t.Fatalf("response type = %v, want an autocomplete result", resp.Type)
+		}
+		got := discordtest.Choices(resp)
+		if len(got) != len(suffixes) {
+			t.Fatalf("choices = %q, want ones ending %q", got, suffixes)
+		}
+		for i, suffix := range suffixes {
+			if !strings.HasSuffix(got[i], suffix) {
+				t.Errorf("choice %d = %q, want it to end %q", i, got[i], suffix)
+			}
+		}
+	}
+}
+
+func wantUser(userId string, dailyCalories int16, energyUnit string) checkFunc {
+	return func(t *testing.T, resp *discordgo.InteractionResponse, store database.Store) {
+		t.Helper()
+		user, err := store.FetchUserByID(ctx, userId)
+		if err != nil {
+			t.Fatalf("fetching user: %v", err)
+		}
+		if user.DailyCalories != dailyCalories || user.EnergyUnit != energyUnit {
+			t.Errorf("user = %d %q, want %d %q", user.DailyCalories, user.EnergyUnit, dailyCalories, energyUnit)
+		}
+	}
+}
+
+func wantConsumed(userId string, calories int64) checkFunc {
+	return func(t *testing.T, resp *discordgo.InteractionResponse, store database.Store) {
+		t.Helper()
+		consumed, err := store.FetchConsumedCaloriesForDate(ctx, userId, time.Now())
+		if err != nil || consumed != calories {
+			t.Errorf("consumed = %d, %v, want %d", consumed, err, calories)
+		}
+	}
+}
+
+func wantSavedFood(userId string, name string, calories int16) checkFunc {
+	return func(t *testing.T, resp *discordgo.InteractionResponse, store database.Store) {
+		t.Helper()
+		savedFood, err := store.FetchSavedFood(ctx, userId, name)
+		if err != nil || savedFood.Calories != calories {
+			t.Errorf("saved %v = %+v, %v, want %d calories", name, savedFood, err, calories)
+		}
+	}
+}
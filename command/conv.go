@@ -2,32 +2,143 @@ package command
 
 import (
 	"fmt"
-	"log"
 	"math"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/database"
 	"github.com/discordcalorietracker/discord"
 	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/units"
 )
 
-func HandleConvCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userDisplayName := i.Member.User.GlobalName
+func HandleConvCommand(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+	userDisplayName := c.User.GlobalName
 
-	// Convert the slice into a map
-	optionMap := helper.ConvertOptionsToMap(i)
+	labelAmount := c.Options["units"].FloatValue()
+	calories := c.Options["calories"].FloatValue()
+	weight := c.Options["weight"].FloatValue()
+	if labelAmount < minConvAmount || weight < minConvAmount {
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("The units and weight must be at least %v.", minConvAmount), true, nil))
+		return
+	}
+
+	logOpt, logProvided := c.Options["log"]
+	logRequested := logProvided && logOpt.BoolValue()
+
+	foodItem, foodItemProvided := c.Options["fooditem"]
+	if !foodItemProvided && logRequested {
+		logger.Info("User tried to log a conversion without a food item name")
+		c.Respond(discord.CreateInteractionResponse("Provide the fooditem name to add the conversion to your log.", true, nil))
+		return
+	}
+
+	if energyOpt, ok := c.Options["energy"]; ok {
+		calories = units.ToKcal(calories, energyOpt.StringValue())
+	}
+
+	// Without units both sides are assumed to be the same, when only one is given the other matches it
+	labelUnit, weightUnit := "", ""
+	if labelUnitOpt, ok := c.Options["labelunit"]; ok {
+		labelUnit = labelUnitOpt.StringValue()
+	}
+	if weightUnitOpt, ok := c.Options["weightunit"]; ok {
+		weightUnit = weightUnitOpt.StringValue()
+	}
+	if labelUnit == "" {
+		labelUnit = weightUnit
+	}
+	if weightUnit == "" {
+		weightUnit = labelUnit
+	}
+
+	unitName := "unit"
+	// Calories per gram or millilitre, which is what gets remembered for the food
+	baseFactor := 1.0
+	if labelUnit != "" {
+		density := 0.0
+		if foodItemProvided {
+			density, _ = units.DensityFor(foodItem.StringValue())
+		}
+
+		converted, convertErr := units.Convert(weight, weightUnit, labelUnit, density)
+		if convertErr != nil {
+			logger.Info("Could not convert between units", "from", weightUnit, "to", labelUnit, "error", convertErr)
+			c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Can't convert %v to %v without knowing the density of the food, provide a known fooditem like milk or flour or use the same kind of unit on both sides.", units.Units[weightUnit].Name, units.Units[labelUnit].Name), true, nil))
+			return
+		}
+
+		weight = converted
+		unitName = units.Units[labelUnit].Name
+		baseFactor = units.Units[labelUnit].Base
+	}
+
+	user := c.Account
+
+	perUnit, totalCalories := helper.ConvertCalories(labelAmount, calories, weight)
+
+	// The result is shown in the users energy unit whatever unit the label was in
+	energyName := units.EnergyName(user.EnergyUnit)
+	result := fmt.Sprintf("%.2f %s per %s \nTotal amount of %s is %.0f", units.FromKcal(perUnit, user.EnergyUnit), energyName, unitName, energyName, math.Ceil(units.FromKcal(totalCalories, user.EnergyUnit)))
+
+	if !foodItemProvided {
+		logger.Debug("User did not provide the optional food item name when converting")
+		c.Respond(discord.CreateInteractionResponse(result, false, nil))
+		return
+	}
+
+	logger.Debug("User provided the optional food item name when converting")
 
-	units := optionMap["units"].FloatValue()
-	calories := optionMap["calories"].FloatValue()
-	weight := optionMap["weight"].FloatValue()
+	foodLog := database.FoodLog{
+		UserID:   userId,
+		FoodItem: foodItem.StringValue(),
+		Quantity: 1,
+	}
+	foodLog = withCalories(foodLog, math.Ceil(totalCalories), 1)
+
+	if logRequested {
+		if !user.HasDailyCalories() {
+			logger.Info("User tried to log a conversion without calling /set first")
+			c.Respond(discord.CreateInteractionResponse("Set your daily calories first using the /set command.", true, nil))
+			return
+		}
+		if !discord.CheckDailyEntries(c) {
+			return
+		}
+
+		id, addFoodLogErr := helper.AddFoodLogAndUpdateStreak(c, c.Store, user, &foodLog)
+		if addFoodLogErr != nil {
+			logger.Error("Error adding converted food log", "error", addFoodLogErr)
+			c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+			return
+		}
+
+		savedFood := database.SavedFood{
+			UserID:          userId,
+			Name:            foodLog.FoodItem,
+			CaloriesPerUnit: perUnit / baseFactor,
+		}
+		if saveErr := c.Store.SaveUserFood(c, &savedFood); saveErr != nil {
+			logger.Error("Error saving food", logging.FoodKey, foodLog.FoodItem, "error", saveErr)
+		}
 
-	perUnit := calories / units
-	totalCalories := perUnit * weight
+		messageComponents := helper.CreateAddRemoveUpdateButtons(userId, id, foodLog.FoodItem)
 
-	if foodItem, ok := optionMap["fooditem"]; ok {
-		log.Printf("User %v provided the optional food item name when converting.", userDisplayName)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse(fmt.Sprintf("%v\n%.2f calories per unit \nTotal amount of calories is %.0f", foodItem.StringValue(), perUnit, math.Ceil(totalCalories)), false, nil))
-	} else {
-		log.Printf("User %v did not provide the optional food item name when converting.", userDisplayName)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse(fmt.Sprintf("%.2f calories per unit \nTotal amount of calories is %.0f", perUnit, math.Ceil(totalCalories)), false, nil))
+		logger.Info("Added converted food log", "log_id", id)
+		helper.DisplayFoodLogEmbed(c, userId, userDisplayName, time.Now(), messageComponents, true)
+		return
 	}
+
+	messageComponents := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				helper.CreateConvLogButton(userId, &foodLog, perUnit/baseFactor),
+			},
+		},
+	}
+
+	c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("%v\n%s", foodItem.StringValue(), result), false, messageComponents))
 }
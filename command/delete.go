@@ -2,37 +2,36 @@ package command
 
 import (
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/discordcalorietracker/database"
 	"github.com/discordcalorietracker/discord"
 	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
 )
 
-func HandleDeleteCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userId := i.Member.User.ID
-	userDisplayName := i.Member.User.GlobalName
+func HandleDeleteCommand(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+	userDisplayName := c.User.GlobalName
 
-	// Convert the slice into a map
-	optionMap := helper.ConvertOptionsToMap(i)
+	logId := c.Options["logid"].IntValue()
 
-	logId := optionMap["logid"].IntValue()
-
-	n, deleteErr := database.DeleteUserFoodLog(userId, logId)
+	n, deleteErr := c.Store.DeleteUserFoodLog(c, userId, logId)
 	if deleteErr != nil {
-		log.Printf("Error deleting food log for user %v: %v", userDisplayName, deleteErr)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		logger.Error("Error deleting food log", "log_id", logId, "error", deleteErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
 		return
 	}
 
 	if n == 0 {
-		log.Printf("Could not find a food log with ID %v for user %v.", logId, userDisplayName)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse(fmt.Sprintf("Could not find a food log with ID %v.", logId), true, nil))
+		logger.Info("Could not find the food log", "log_id", logId)
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Could not find a food log with ID %v.", logId), true, nil))
 		return
 	}
 
-	log.Printf("Deleted food log %v for user %v and retrieved remaining calories.", logId, userDisplayName)
-	helper.DisplayFoodLogEmbed(s, i, userId, userDisplayName, time.Now(), nil, true)
+	messageComponents := []discordgo.MessageComponent{helper.CreateUndoButton(userId, logId)}
+
+	logger.Info("Deleted food log", "log_id", logId)
+	helper.DisplayFoodLogEmbed(c, userId, userDisplayName, time.Now(), messageComponents, true)
 }
@@ -0,0 +1,74 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/units"
+)
+
+func HandleFoodCommand(c *discord.Context) {
+	subcommand := c.Interaction.ApplicationCommandData().Options[0]
+
+	switch subcommand.Name {
+	case "search":
+		handleFoodSearch(c, subcommand.Options[0].StringValue())
+	}
+}
+
+func handleFoodSearch(c *discord.Context, query string) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+	user := c.Account
+
+	logger.Debug("Searching nutrition data", logging.TextKey, query)
+	results, searchErr := c.Store.SearchNutrition(c, query, maxQuickLogItems)
+	if searchErr != nil {
+		logger.Error("Error searching nutrition data", "error", searchErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	if len(results) == 0 {
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("No foods found matching %q.", query), true, nil))
+		return
+	}
+
+	var content strings.Builder
+	var messageComponents []discordgo.MessageComponent
+
+	for index, nutrition := range results {
+		grams := nutrition.ServingGrams
+		if grams == 0 {
+			grams = 100
+		}
+		calories := nutrition.KcalPer100g * grams / 100
+
+		content.WriteString(fmt.Sprintf("**%d. %s** - %v per 100g", index+1, nutrition.Name, units.FormatEnergy(nutrition.KcalPer100g, user.EnergyUnit)))
+		if nutrition.ServingGrams > 0 {
+			content.WriteString(fmt.Sprintf(", serving %gg is %v", nutrition.ServingGrams, units.FormatEnergy(calories, user.EnergyUnit)))
+		}
+		content.WriteString("\n")
+
+		foodLog := database.FoodLog{
+			UserID:   userId,
+			FoodItem: helper.WeighedFoodItem(nutrition.Name, fmt.Sprintf("%gg", math.Round(grams))),
+			Quantity: 1,
+		}
+		foodLog = withCalories(foodLog, calories, 1)
+		messageComponents = append(messageComponents, helper.CreateQuickLogButton(userId, &foodLog, true))
+	}
+
+	logger.Info("Found matching foods", "count", len(results))
+	c.Respond(discord.CreateInteractionResponse(content.String(), true, []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: messageComponents,
+		},
+	}))
+}
@@ -0,0 +1,39 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+)
+
+func HandleHistoryCommand(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+
+	logId := c.Options["logid"].IntValue()
+
+	history, historyErr := c.Store.FetchFoodLogHistory(c, userId, logId)
+	if historyErr != nil {
+		logger.Error("Error fetching food log history", "log_id", logId, "error", historyErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	if len(history) == 0 {
+		logger.Info("Could not find the food log history", "log_id", logId)
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Could not find a food log with ID %v.", logId), true, nil))
+		return
+	}
+
+	logger.Info("Showing food log history", "log_id", logId, "changes", len(history))
+	c.Respond(&discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{helper.CreateHistoryEmbed(logId, history, c.Account.EnergyUnit)},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
@@ -2,47 +2,44 @@ package command
 
 import (
 	"fmt"
-	"log"
 	"time"
 
-	"github.com/bwmarrin/discordgo"
 	"github.com/discordcalorietracker/discord"
 	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
 )
 
-func HandleListCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userId := i.Member.User.ID
-	userDisplayName := i.Member.User.GlobalName
+func HandleListCommand(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+	userDisplayName := c.User.GlobalName
 
-	// Convert the slice into a map
-	optionMap := helper.ConvertOptionsToMap(i)
-
-	userParam, userProvided := optionMap["user"]
+	userParam, userProvided := c.Options["user"]
 	if userProvided {
-		user := userParam.UserValue(s)
+		user := c.UserOption(userParam)
 		isBot := user.Bot
 		userId = user.ID
 		userDisplayName = user.GlobalName
 
 		if isBot {
-			log.Printf("User %v has requested to see a bots list which isn't valid.", i.Member.User.GlobalName)
-			s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("That is a bot, please select a user.", true, nil))
+			logger.Info("User requested the list of a bot")
+			c.Respond(discord.CreateInteractionResponse("That is a bot, please select a user.", true, nil))
 			return
 		}
-		log.Printf("User %v has requested to see the list of user %v.", i.Member.User.GlobalName, userDisplayName)
+		logger.Info("User requested the list of another user", "list_user_id", userId)
 	}
 
 	startDate := time.Now()
-	dateCmd, dateItemExists := optionMap["date"]
+	dateCmd, dateItemExists := c.Options["date"]
 	if dateItemExists {
-		date, dateParseErr := time.Parse("02/01/2006", dateCmd.StringValue())
+		date, dateParseErr := time.Parse(helper.DATEFORMAT, dateCmd.StringValue())
 		if dateParseErr != nil {
-			log.Printf("Error parsing for user with username %v. Error: %v", userDisplayName, dateParseErr)
-			s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse(fmt.Sprintf("Error parsing date, please try again with format like %v.", startDate.Format(helper.DATEFORMAT)), true, nil))
+			logger.Info("Could not parse the date", "error", dateParseErr)
+			c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Error parsing date, please try again with format like %v.", startDate.Format(helper.DATEFORMAT)), true, nil))
 			return
 		}
 		startDate = date
 	}
 
-	helper.DisplayFoodLogEmbed(s, i, userId, userDisplayName, startDate, nil, false)
+	helper.DisplayFoodLogEmbed(c, userId, userDisplayName, startDate, nil, false)
 }
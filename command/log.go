@@ -0,0 +1,127 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/parser"
+	"github.com/discordcalorietracker/units"
+)
+
+// Discord allows at most 5 buttons in a single action row
+const maxQuickLogItems = 5
+
+func HandleLogCommand(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+	user := c.Account
+
+	text := c.Options["text"].StringValue()
+	items := parser.Parse(text)
+	if len(items) == 0 {
+		logger.Info("Could not parse any food items", logging.TextKey, text)
+		c.Respond(discord.CreateInteractionResponse("Could not find any food in that, try something like `2 slices toast and a banana`.", true, nil))
+		return
+	}
+
+	if len(items) > maxQuickLogItems {
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("You can quick log at most %d items at once.", maxQuickLogItems), true, nil))
+		return
+	}
+
+	var content strings.Builder
+	var messageComponents []discordgo.MessageComponent
+
+	for _, item := range items {
+		foodLog, found, err := resolveQuickLogItem(c, c.Store, userId, item)
+		_, weighed := item.Grams()
+		if err != nil {
+			logger.Error("Error resolving quick log item", logging.FoodKey, item.Name, "error", err)
+			c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+			return
+		}
+
+		if !found {
+			content.WriteString(fmt.Sprintf("❔ %s - no match found, use /add instead\n", item.Name))
+			continue
+		}
+
+		content.WriteString(fmt.Sprintf("✅ %s - %v\n", helper.FoodLogName(foodLog), units.FormatEnergy(helper.FoodLogCalories(foodLog), user.EnergyUnit)))
+		messageComponents = append(messageComponents, helper.CreateQuickLogButton(userId, &foodLog, weighed))
+	}
+
+	if len(messageComponents) == 0 {
+		logger.Info("No quick log items matched", logging.TextKey, text)
+		c.Respond(discord.CreateInteractionResponse(content.String(), true, nil))
+		return
+	}
+
+	content.WriteString("\nPress a button to add the entry to your log.")
+
+	logger.Info("Proposed quick log entries", "count", len(messageComponents))
+	c.Respond(discord.CreateInteractionResponse(content.String(), true, []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: messageComponents,
+		},
+	}))
+}
+
+// resolveQuickLogItem works out the calories of a parsed item, first from the users saved foods and then from the nutrition database.
+func resolveQuickLogItem(ctx context.Context, store database.Store, userId string, item parser.Item) (database.FoodLog, bool, error) {
+	foodLog := database.FoodLog{
+		UserID:   userId,
+		FoodItem: item.Singular(),
+		Quantity: 1,
+		Serving:  item.Serving(),
+	}
+
+	grams, isWeight := item.Grams()
+
+	for _, name := range []string{item.Name, item.Singular()} {
+		savedFood, err := store.FetchSavedFood(ctx, userId, name)
+		if err != nil {
+			return foodLog, false, err
+		}
+
+		if isWeight && savedFood.CaloriesPerUnit > 0 {
+			foodLog.FoodItem = helper.WeighedFoodItem(savedFood.Name, fmt.Sprintf("%g%s", item.Quantity, item.Unit))
+			return withCalories(foodLog, savedFood.CaloriesPerUnit*grams, 1), true, nil
+		}
+
+		if !isWeight && savedFood.Calories > 0 {
+			foodLog.FoodItem = savedFood.Name
+			return withCalories(foodLog, float64(savedFood.Calories), item.Quantity), true, nil
+		}
+	}
+
+	nutrition, err := store.FetchNutritionByName(ctx, item.Singular())
+	if err != nil || nutrition.ID == 0 {
+		return foodLog, false, err
+	}
+
+	if isWeight {
+		foodLog.FoodItem = helper.WeighedFoodItem(nutrition.Name, fmt.Sprintf("%g%s", item.Quantity, item.Unit))
+		return withCalories(foodLog, nutrition.KcalPer100g*grams/100, 1), true, nil
+	}
+
+	if nutrition.ServingGrams == 0 {
+		return foodLog, false, nil
+	}
+
+	foodLog.FoodItem = nutrition.Name
+	return withCalories(foodLog, nutrition.KcalPer100g*nutrition.ServingGrams/100, item.Quantity), true, nil
+}
+
+// withCalories sets the calories of a single serving of the food log and how many servings were eaten, which can be a fraction.
+func withCalories(foodLog database.FoodLog, calories float64, quantity float64) database.FoodLog {
+	foodLog.Calories = int16(math.Round(math.Max(math.Min(calories, maxItemCalories), minCalorieIntake)))
+	foodLog.Quantity = math.Max(math.Min(math.Round(quantity*100)/100, maxQuantity), minQuantity)
+	return foodLog
+}
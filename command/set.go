@@ -2,35 +2,37 @@ package command
 
 import (
 	"fmt"
-	"log"
 
-	"github.com/bwmarrin/discordgo"
 	"github.com/discordcalorietracker/database"
 	"github.com/discordcalorietracker/discord"
 	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/units"
 )
 
-func HandleSetCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userId := i.Member.User.ID
-	userDisplayName := i.Member.User.GlobalName
+func HandleSetCommand(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+	existingUser := c.Account
 
-	// Convert the slice into a map
-	optionMap := helper.ConvertOptionsToMap(i)
-
-	calories := optionMap["calories"].IntValue()
+	calories, inRange := helper.ToKcal(c.Options["calories"].IntValue(), existingUser.EnergyUnit)
+	if !inRange {
+		c.Respond(discord.CreateInteractionResponse(helper.OutOfRangeMessage(existingUser.EnergyUnit), true, nil))
+		return
+	}
 
 	user := database.User{
 		ID:            userId,
-		DailyCalories: int16(calories),
+		DailyCalories: calories,
 	}
 
-	_, setCaloriesErr := database.SetUserCalories(&user)
+	setCaloriesErr := c.Store.SetUserCalories(c, &user)
 	if setCaloriesErr != nil {
-		log.Printf("Error setting calories for user with ID %v and username %v. Error: %v", userId, userDisplayName, setCaloriesErr)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		logger.Error("Error setting calories", "error", setCaloriesErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
 		return
 	}
 
-	log.Printf("Successfully set daily calorie intake to %d for user with ID %v and username %v.", calories, userId, userDisplayName)
-	s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse(fmt.Sprintf("Your daily calorie intake has successfully been set to %d.", calories), true, nil))
+	logger.Info("Set daily calorie intake", "calories", calories)
+	c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Your daily intake has successfully been set to %v.", units.FormatEnergy(float64(calories), existingUser.EnergyUnit)), true, nil))
 }
@@ -0,0 +1,130 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/units"
+)
+
+// Keeps the list inside Discord's message length limit
+const maxTrashListed = 20
+
+// trashDays is how many days entries can be restored for, shown to users.
+var trashDays = int(database.TrashRetention.Hours() / 24)
+
+func HandleTrashCommand(c *discord.Context) {
+	subcommand := c.Interaction.ApplicationCommandData().Options[0]
+
+	switch subcommand.Name {
+	case "list":
+		handleTrashList(c)
+	case "restore":
+		handleTrashRestore(c, subcommand.Options[0].IntValue())
+	case "empty":
+		handleTrashEmpty(c)
+	}
+}
+
+func handleTrashList(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+	user := c.Account
+
+	foodLogs, trashErr := c.Store.FetchTrashedFoodLogs(c, userId)
+	if trashErr != nil {
+		logger.Error("Error fetching trashed food logs", "error", trashErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	if len(foodLogs) == 0 {
+		c.Respond(discord.CreateInteractionResponse("Your trash is empty.", true, nil))
+		return
+	}
+
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("Deleted entries can be put back with /trash restore for %d days:\n", trashDays))
+	for i, foodLog := range foodLogs {
+		if i == maxTrashListed {
+			content.WriteString(fmt.Sprintf("...and %d more.\n", len(foodLogs)-maxTrashListed))
+			break
+		}
+
+		energy := units.FormatEnergy(helper.FoodLogCalories(foodLog), user.EnergyUnit)
+		content.WriteString(fmt.Sprintf("(%d) %s, %s, deleted <t:%d:R>\n", foodLog.ID, helper.FoodLogName(foodLog), energy, foodLog.DeletedAt.Unix()))
+	}
+
+	logger.Info("Listed trashed food logs", "count", len(foodLogs))
+	c.Respond(discord.CreateInteractionResponse(content.String(), true, nil))
+}
+
+func handleTrashRestore(c *discord.Context, logId int64) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+	userDisplayName := c.User.GlobalName
+
+	foodLogs, trashErr := c.Store.FetchTrashedFoodLogs(c, userId)
+	if trashErr != nil {
+		logger.Error("Error fetching trashed food logs", "error", trashErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	var restored *database.FoodLog
+	for i := range foodLogs {
+		if foodLogs[i].ID == logId {
+			restored = &foodLogs[i]
+		}
+	}
+
+	if restored == nil {
+		logger.Info("Could not find the food log in the trash", "log_id", logId)
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Could not find a food log with ID %v in your trash.", logId), true, nil))
+		return
+	}
+
+	n, restoreErr := c.Store.RestoreFoodLog(c, userId, logId)
+	if restoreErr != nil {
+		logger.Error("Error restoring food log", "log_id", logId, "error", restoreErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	// The trash was emptied or the entry restored by another interaction since it was fetched
+	if n == 0 {
+		logger.Info("Food log left the trash before it could be restored", "log_id", logId)
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("The food log with ID %v is no longer in your trash.", logId), true, nil))
+		return
+	}
+
+	messageComponents := helper.CreateAddRemoveUpdateButtons(userId, logId, restored.FoodItem)
+
+	// The entry goes back to the day it was logged on
+	logger.Info("Restored food log", "log_id", logId)
+	helper.DisplayFoodLogEmbed(c, userId, userDisplayName, restored.DateTime, messageComponents, true)
+}
+
+func handleTrashEmpty(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+
+	n, emptyErr := c.Store.EmptyTrash(c, userId)
+	if emptyErr != nil {
+		logger.Error("Error emptying the trash", "error", emptyErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	if n == 0 {
+		c.Respond(discord.CreateInteractionResponse("Your trash is already empty.", true, nil))
+		return
+	}
+
+	logger.Info("Emptied the trash", "count", n)
+	c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Permanently deleted %d entries from your trash.", n), true, nil))
+}
@@ -0,0 +1,10 @@
+package command
+
+import (
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+)
+
+func HandleUndoCommand(c *discord.Context) {
+	helper.UndoFoodLogChange(c, c.User.ID, 0)
+}
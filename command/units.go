@@ -0,0 +1,26 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/units"
+)
+
+func HandleUnitsCommand(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+
+	energyUnit := c.Options["energy"].StringValue()
+
+	_, setUnitErr := c.Store.SetUserEnergyUnit(c, userId, energyUnit)
+	if setUnitErr != nil {
+		logger.Error("Error setting energy unit", "error", setUnitErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	logger.Info("Set energy unit", "energy_unit", energyUnit)
+	c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Energy will now be shown and entered in %v.", units.EnergyName(energyUnit)), true, nil))
+}
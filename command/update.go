@@ -2,53 +2,96 @@ package command
 
 import (
 	"fmt"
-	"log"
+	"strings"
 	"time"
 
-	"github.com/bwmarrin/discordgo"
-	"github.com/discordcalorietracker/database"
 	"github.com/discordcalorietracker/discord"
 	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
 )
 
-func HandleUpdateCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userId := i.Member.User.ID
-	userDisplayName := i.Member.User.GlobalName
+func HandleUpdateCommand(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userId := c.User.ID
+	userDisplayName := c.User.GlobalName
+	user := c.Account
 
-	optionMap := helper.ConvertOptionsToMap(i)
+	logId := c.Options["logid"].IntValue()
+	foodItemOpt, hasFoodItem := c.Options["fooditem"]
+	caloriesOpt, hasCalories := c.Options["calories"]
+	quantityOpt, hasQuantity := c.Options["quantity"]
+	servingOpt, hasServing := c.Options["serving"]
 
-	logId := optionMap["logid"].IntValue()
-	foodItem := optionMap["fooditem"].StringValue()
-	calories := optionMap["calories"].IntValue()
+	if !hasFoodItem && !hasCalories && !hasQuantity && !hasServing {
+		c.Respond(discord.CreateInteractionResponse("Provide a new food item, calories, quantity or serving to update the entry.", true, nil))
+		return
+	}
+
+	var calories int16
+	if hasCalories {
+		var inRange bool
+		calories, inRange = helper.ToKcal(caloriesOpt.IntValue(), user.EnergyUnit)
+		if !inRange {
+			c.Respond(discord.CreateInteractionResponse(helper.OutOfRangeMessage(user.EnergyUnit), true, nil))
+			return
+		}
+	}
 
-	foodLog := database.FoodLog{
-		ID:       logId,
-		UserID:   userId,
-		FoodItem: foodItem,
-		Calories: int16(calories),
-		Quantity: 1,
+	var quantity float64
+	if hasQuantity {
+		var inRange bool
+		quantity, inRange = helper.RoundQuantity(quantityOpt.FloatValue())
+		if !inRange {
+			c.Respond(discord.CreateInteractionResponse(helper.QuantityOutOfRangeMessage(), true, nil))
+			return
+		}
+	}
+
+	foodLog, fetchErr := c.Store.FetchFoodLog(c, userId, logId)
+	if fetchErr != nil {
+		logger.Error("Error fetching food log", "log_id", logId, "error", fetchErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
 	}
 
-	if quantity, ok := optionMap["quantity"]; ok {
-		itemQuantity := int16(quantity.IntValue())
-		foodLog.Quantity = itemQuantity
+	if foodLog.ID == 0 {
+		logger.Info("Could not find the food log", "log_id", logId)
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Could not find a food log with ID %v.", logId), true, nil))
+		return
+	}
+
+	// Only the provided fields change, the rest are kept as they were
+	if hasFoodItem {
+		foodLog.FoodItem = foodItemOpt.StringValue()
+	}
+	if hasCalories {
+		foodLog.Calories = calories
+	}
+	if hasQuantity {
+		foodLog.Quantity = quantity
+	}
+	if hasServing {
+		foodLog.Serving = strings.TrimSpace(servingOpt.StringValue())
+		if strings.EqualFold(foodLog.Serving, "none") {
+			foodLog.Serving = ""
+		}
 	}
 
-	n, updateErr := database.UpdateUserFoodLog(&foodLog)
+	n, updateErr := c.Store.UpdateUserFoodLog(c, &foodLog)
 	if updateErr != nil {
-		log.Printf("Error updating food log with ID %v for user %v: %v", logId, userDisplayName, updateErr)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		logger.Error("Error updating food log", "log_id", logId, "error", updateErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
 		return
 	}
 
 	if n == 0 {
-		log.Printf("Could not find a food log with ID %v for user %v.", logId, userDisplayName)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse(fmt.Sprintf("Could not find a food log with ID %v.", logId), true, nil))
+		logger.Info("Could not find the food log", "log_id", logId)
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Could not find a food log with ID %v.", logId), true, nil))
 		return
 	}
 
 	messageComponents := helper.CreateAddRemoveUpdateButtons(userId, logId, foodLog.FoodItem)
 
-	log.Printf("Updated food log %v for user %v and retrieved remaining calories.", logId, userDisplayName)
-	helper.DisplayFoodLogEmbed(s, i, userId, userDisplayName, time.Now(), messageComponents, true)
+	logger.Info("Updated food log", "log_id", logId)
+	helper.DisplayFoodLogEmbed(c, userId, userDisplayName, time.Now(), messageComponents, true)
 }
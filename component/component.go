@@ -1,9 +1,23 @@
 package component
 
-import "github.com/bwmarrin/discordgo"
+import "github.com/discordcalorietracker/discord"
 
-var ComponentHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
-	"flquantity": HandleModifyFoodQuantity,
+// Buttons carry their owners ID, conversions are posted publicly so anyone could otherwise press them
+const (
+	entryOwnerMessage      = "Only the user who logged this entry can change it."
+	conversionOwnerMessage = "Only the user who ran the conversion can add it to their log."
+)
+
+// ModalComponents open a modal rather than responding with a message
+var ModalComponents = []string{"fledit"}
+
+var ComponentHandlers = map[string]discord.Handler{
+	"flquantity": discord.Chain(HandleModifyFoodQuantity, discord.RequireOwner(2, entryOwnerMessage)),
 	"fllist":     HandleUpdateList,
-	"fldel":      HandleDeleteLog,
+	"fldel":      discord.Chain(HandleDeleteLog, discord.RequireOwner(1, entryOwnerMessage)),
+	"qlog":       discord.Chain(HandleQuickLogAdd, discord.RequireOwner(1, entryOwnerMessage), discord.RequireDailyCalories, discord.LimitDailyEntries),
+	"convlog":    discord.Chain(HandleConvLogAdd, discord.RequireOwner(1, conversionOwnerMessage), discord.RequireDailyCalories, discord.LimitDailyEntries),
+	"undo":       discord.Chain(HandleUndo, discord.RequireOwner(1, entryOwnerMessage)),
+	"fledit":     discord.Chain(HandleEditLog, discord.RequireOwner(1, entryOwnerMessage)),
+	"fleditsave": discord.Chain(HandleEditLogSubmit, discord.RequireOwner(1, entryOwnerMessage)),
 }
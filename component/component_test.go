@@ -0,0 +1,386 @@
+package component
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/discord/discordtest"
+	"github.com/discordcalorietracker/helper"
+)
+
+var (
+	ctx = context.Background()
+
+	alice = &discordgo.User{ID: "100", GlobalName: "Alice"}
+	bob   = &discordgo.User{ID: "200", GlobalName: "Bob"}
+)
+
+func TestComponents(t *testing.T) {
+	tests := []struct {
+		name      string
+		setUser   bool
+		logs      []database.FoodLog
+		press     *discordgo.InteractionCreate
+		content   string
+		embed     []string
+		ephemeral bool
+		consumed  int64
+		savedFood *database.SavedFood
+	}{
+		{
+			name:      "quick log adds the entry and remembers the food",
+			setUser:   true,
+			press:     discordtest.Component(alice, "qlog_100_250_2_s__Toast"),
+			embed:     []string{"(1) x2 Toast", "**Total Consumed**: 500"},
+			ephemeral: true,
+			consumed:  500,
+			savedFood: &database.SavedFood{Name: "toast", Calories: 250},
+		},
+		{
+			name:      "quick log keeps the serving size",
+			setUser:   true,
+			press:     discordtest.Component(alice, "qlog_100_80_2_s_slice_Toast"),
+			embed:     []string{"(1) x2 Toast (slice)"},
+			ephemeral: true,
+			consumed:  160,
+		},
+		{
+			name:      "quick log doesn't remember weighed entries",
+			setUser:   true,
+			press:     discordtest.Component(alice, "qlog_100_105_1_w__banana (118g)"),
+			embed:     []string{"(1) banana (118g)"},
+			ephemeral: true,
+			consumed:  105,
+			savedFood: &database.SavedFood{Name: "banana (118g)"},
+		},
+		{
+			name:      "quick log remembers whole servings with brackets in the name",
+			setUser:   true,
+			press:     discordtest.Component(alice, "qlog_100_350_1_s__Pad Thai (veg)"),
+			embed:     []string{"(1) Pad Thai (veg)"},
+			ephemeral: true,
+			consumed:  350,
+			savedFood: &database.SavedFood{Name: "pad thai (veg)", Calories: 350},
+		},
+		{
+			name:      "quick log shortens long names so they can be edited",
+			setUser:   true,
+			press:     discordtest.Component(alice, "qlog_100_200_1_s__Extra Large Chocolate Chip Cookie With Walnuts And Sea Salt"),
+			embed:     []string{"(1) Extra Large Chocolate Chip Cookie With Walnuts And\n"},
+			ephemeral: true,
+			consumed:  200,
+			savedFood: &database.SavedFood{Name: "extra large chocolate chip cookie with walnuts and", Calories: 200},
+		},
+		{
+			name:      "quick log keeps underscores in the name",
+			setUser:   true,
+			press:     discordtest.Component(alice, "qlog_100_90_1_s__snack_bar"),
+			embed:     []string{"(1) snack_bar"},
+			ephemeral: true,
+			consumed:  90,
+		},
+		{
+			name:      "quick log needs set first",
+			press:     discordtest.Component(alice, "qlog_100_250_1_s__Toast"),
+			content:   "Set your daily calories first using the /set command.",
+			ephemeral: true,
+		},
+		{
+			name:      "conversion log adds the entry and remembers the calories per unit",
+			setUser:   true,
+			press:     discordtest.Component(alice, "convlog_100_135_4.5000_Digestive"),
+			embed:     []string{"(1) Digestive", "**Total Consumed**: 135"},
+			ephemeral: true,
+			consumed:  135,
+			savedFood: &database.SavedFood{Name: "digestive", CaloriesPerUnit: 4.5},
+		},
+		{
+			name:      "conversion log with a malformed custom ID",
+			setUser:   true,
+			press:     discordtest.Component(alice, "convlog_100_135"),
+			content:   "There was an error, please try again...",
+			ephemeral: true,
+		},
+		{
+			name:      "conversion log with a bad calorie count",
+			setUser:   true,
+			press:     discordtest.Component(alice, "convlog_100_lots_4.5000_Digestive"),
+			content:   "There was an error, please try again...",
+			ephemeral: true,
+		},
+		{
+			name:      "conversion log works in DMs",
+			setUser:   true,
+			press:     discordtest.DirectMessage(discordtest.Component(alice, "convlog_100_135_4.5000_Digestive"), discordgo.InteractionContextBotDM),
+			embed:     []string{"(1) Digestive"},
+			ephemeral: true,
+			consumed:  135,
+		},
+		{
+			name:      "conversion log only works for its author",
+			setUser:   true,
+			press:     discordtest.Component(bob, "convlog_100_135_4.5000_Digestive"),
+			content:   "Only the user who ran the conversion can add it to their log.",
+			ephemeral: true,
+		},
+		{
+			name:      "increasing the quantity",
+			setUser:   true,
+			logs:      []database.FoodLog{{FoodItem: "Toast", Calories: 250, Quantity: 1}},
+			press:     discordtest.Component(alice, "flquantity_inc_100_1_Toast"),
+			embed:     []string{"(1) x2 Toast"},
+			ephemeral: true,
+			consumed:  500,
+		},
+		{
+			name:      "the quantity can't go below one",
+			setUser:   true,
+			logs:      []database.FoodLog{{FoodItem: "Toast", Calories: 250, Quantity: 1}},
+			press:     discordtest.Component(alice, "flquantity_dec_100_1_Toast"),
+			content:   "Failed to update the quantity for food log with ID 1.",
+			ephemeral: true,
+			consumed:  250,
+		},
+		{
+			name:    "deleting an entry",
+			setUser: true,
+			logs: []database.FoodLog{
+				{FoodItem: "Toast", Calories: 250, Quantity: 1},
+				{FoodItem: "Banana", Calories: 105, Quantity: 1},
+			},
+			press:     discordtest.Component(alice, "fldel_100_1_Toast"),
+			embed:     []string{"(2) Banana", "**Total Consumed**: 105"},
+			ephemeral: true,
+			consumed:  105,
+		},
+		{
+			name:      "entries can only be changed by their owner",
+			setUser:   true,
+			logs:      []database.FoodLog{{FoodItem: "Toast", Calories: 250, Quantity: 1}},
+			press:     discordtest.Component(bob, "flquantity_inc_100_1_Toast"),
+			content:   "Only the user who logged this entry can change it.",
+			ephemeral: true,
+			consumed:  250,
+		},
+		{
+			name:      "undoing a new entry removes it",
+			setUser:   true,
+			logs:      []database.FoodLog{{FoodItem: "Toast", Calories: 250, Quantity: 1}},
+			press:     discordtest.Component(alice, "undo_100_1"),
+			content:   "Undid a change to entry 1: Added Toast (250 calories).",
+			ephemeral: true,
+		},
+		{
+			name:      "undo only works for the entries owner",
+			setUser:   true,
+			logs:      []database.FoodLog{{FoodItem: "Toast", Calories: 250, Quantity: 1}},
+			press:     discordtest.Component(bob, "undo_100_1"),
+			content:   "Only the user who logged this entry can change it.",
+			ephemeral: true,
+			consumed:  250,
+		},
+		{
+			name:      "editing saves the modal",
+			setUser:   true,
+			logs:      []database.FoodLog{{FoodItem: "Toast", Calories: 250, Quantity: 1}},
+			press:     discordtest.ModalSubmit(alice, "fleditsave_100_1", map[string]string{"fooditem": "Brown Toast", "calories": "300", "quantity": "1.5", "serving": "slice"}),
+			embed:     []string{"(1) x1.5 Brown Toast (slice)", "**Total Consumed**: 450"},
+			ephemeral: true,
+			consumed:  450,
+		},
+		{
+			name:      "editing rejects a quantity that isn't a number",
+			setUser:   true,
+			logs:      []database.FoodLog{{FoodItem: "Toast", Calories: 250, Quantity: 1}},
+			press:     discordtest.ModalSubmit(alice, "fleditsave_100_1", map[string]string{"fooditem": "Toast", "calories": "250", "quantity": "two"}),
+			content:   "The quantity must be a number between 0.01 and 1000.",
+			ephemeral: true,
+			consumed:  250,
+		},
+		{
+			name:      "editing only works for the entries owner",
+			setUser:   true,
+			logs:      []database.FoodLog{{FoodItem: "Toast", Calories: 250, Quantity: 1}},
+			press:     discordtest.ModalSubmit(bob, "fleditsave_100_1", map[string]string{"fooditem": "Stolen", "calories": "1", "quantity": "1"}),
+			content:   "Only the user who logged this entry can change it.",
+			ephemeral: true,
+			consumed:  250,
+		},
+		{
+			name:      "deleting an unknown entry",
+			setUser:   true,
+			press:     discordtest.Component(alice, "fldel_100_7_Toast"),
+			content:   "Could not find a food log with ID 7.",
+			ephemeral: true,
+		},
+		{
+			name:     "refreshing the list",
+			setUser:  true,
+			logs:     []database.FoodLog{{FoodItem: "Toast", Calories: 250, Quantity: 3}},
+			press:    discordtest.Component(alice, "fllist_100_Alice_01/01/2024"),
+			embed:    []string{"Food Log - Alice", "(1) x3 Toast"},
+			consumed: 750,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := discordtest.NewStore(t)
+			if test.setUser {
+				if err := store.SetUserCalories(ctx, &database.User{ID: alice.ID, DailyCalories: 2000}); err != nil {
+					t.Fatalf("setting user: %v", err)
+				}
+			}
+			for _, foodLog := range test.logs {
+				foodLog.UserID = alice.ID
+				if _, err := store.AddUserFoodLog(ctx, &foodLog); err != nil {
+					t.Fatalf("adding food log: %v", err)
+				}
+			}
+
+			responder := &discordtest.Responder{}
+			c := discord.NewContext(ctx, responder, test.press, store)
+			handler, ok := ComponentHandlers[c.Name]
+			if !ok {
+				t.Fatalf("no handler for %v", c.CustomID())
+			}
+			discord.Wrap(handler)(c)
+
+			if len(responder.Responses) != 1 {
+				t.Fatalf("got %d responses, want 1", len(responder.Responses))
+			}
+			resp := responder.Last(t)
+
+			if got := discordtest.Content(resp); got != test.content {
+				t.Errorf("content = %q, want %q", got, test.content)
+			}
+			if discordtest.Ephemeral(resp) != test.ephemeral {
+				t.Errorf("ephemeral = %v, want %v", discordtest.Ephemeral(resp), test.ephemeral)
+			}
+
+			embedText := discordtest.EmbedText(resp)
+			if len(test.embed) == 0 && embedText != "" {
+				t.Errorf("got an embed, want none:\n%v", embedText)
+			}
+			for _, text := range test.embed {
+				if !strings.Contains(embedText, text) {
+					t.Errorf("embed is missing %q, got:\n%v", text, embedText)
+				}
+			}
+
+			foodLogs, err := store.FetchDailyFoodLogs(ctx, alice.ID, time.Now())
+			if err != nil {
+				t.Fatalf("fetching food logs: %v", err)
+			}
+			var consumed float64
+			for _, foodLog := range foodLogs {
+				consumed += float64(foodLog.Calories) * foodLog.Quantity
+			}
+			if int64(math.Round(consumed)) != test.consumed {
+				t.Errorf("consumed = %v, want %d", consumed, test.consumed)
+			}
+
+			if test.savedFood != nil {
+				savedFood, err := store.FetchSavedFood(ctx, alice.ID, test.savedFood.Name)
+				if err != nil || savedFood.Calories != test.savedFood.Calories || savedFood.CaloriesPerUnit != test.savedFood.CaloriesPerUnit {
+					t.Errorf("saved food = %+v, %v, want %+v", savedFood, err, *test.savedFood)
+				}
+			}
+		})
+	}
+}
+
+func TestEditOpensAFilledInModal(t *testing.T) {
+	store := discordtest.NewStore(t)
+	if err := store.SetUserCalories(ctx, &database.User{ID: alice.ID, DailyCalories: 2000}); err != nil {
+		t.Fatalf("setting user: %v", err)
+	}
+	if _, err := store.AddUserFoodLog(ctx, &database.FoodLog{UserID: alice.ID, FoodItem: "Toast", Calories: 250, Quantity: 2}); err != nil {
+		t.Fatalf("adding food log: %v", err)
+	}
+
+	responder := &discordtest.Responder{}
+	discord.Wrap(ComponentHandlers["fledit"])(discord.NewContext(ctx, responder, discordtest.Component(alice, "fledit_100_1"), store))
+
+	resp := responder.Last(t)
+	if resp.Type != discordgo.InteractionResponseModal || resp.Data.CustomID != "fleditsave_100_1" {
+		t.Fatalf("response = %v %q, want the fleditsave modal", resp.Type, resp.Data.CustomID)
+	}
+
+	values := map[string]string{}
+	for _, component := range resp.Data.Components {
+		for _, rowComponent := range component.(discordgo.ActionsRow).Components {
+			input := rowComponent.(discordgo.TextInput)
+			values[input.CustomID] = input.Value
+			if input.MaxLength < len(input.Value) {
+				t.Errorf("%v is filled in with %q, longer than its limit of %d", input.CustomID, input.Value, input.MaxLength)
+			}
+		}
+	}
+	if values["fooditem"] != "Toast" || values["calories"] != "250" || values["quantity"] != "2" || values["serving"] != "" {
+		t.Errorf("modal values = %v, want the entry as it is", values)
+	}
+}
+
+func TestQuantityStep(t *testing.T) {
+	helper.QuantityStep = 0.5
+	t.Cleanup(func() { helper.QuantityStep = 1 })
+
+	store := discordtest.NewStore(t)
+	if err := store.SetUserCalories(ctx, &database.User{ID: alice.ID, DailyCalories: 2000}); err != nil {
+		t.Fatalf("setting user: %v", err)
+	}
+	if _, err := store.AddUserFoodLog(ctx, &database.FoodLog{UserID: alice.ID, FoodItem: "Pizza", Calories: 285, Quantity: 1}); err != nil {
+		t.Fatalf("adding food log: %v", err)
+	}
+
+	responder := &discordtest.Responder{}
+	discord.Wrap(ComponentHandlers["flquantity"])(discord.NewContext(ctx, responder, discordtest.Component(alice, "flquantity_dec_100_1_Pizza"), store))
+
+	resp := responder.Last(t)
+	if text := discordtest.EmbedText(resp); !strings.Contains(text, "(1) x0.5 Pizza") {
+		t.Errorf("embed is missing half a pizza, got:\n%v", text)
+	}
+	if buttons := discordtest.Buttons(resp); len(buttons) < 2 || buttons[1].Label != "Remove 0.5 Pizza" {
+		t.Errorf("buttons = %+v, want the remove button to say it takes off half", buttons)
+	}
+
+	discord.Wrap(ComponentHandlers["flquantity"])(discord.NewContext(ctx, responder, discordtest.Component(alice, "flquantity_dec_100_1_Pizza"), store))
+	if got := discordtest.Content(responder.Last(t)); got != "Failed to update the quantity for food log with ID 1." {
+		t.Errorf("content = %q, want the quantity kept above 0", got)
+	}
+}
+
+func TestButtonsFitDiscordLimits(t *testing.T) {
+	store := discordtest.NewStore(t)
+	if err := store.SetUserCalories(ctx, &database.User{ID: alice.ID, DailyCalories: 2000}); err != nil {
+		t.Fatalf("setting user: %v", err)
+	}
+	foodItem := strings.Repeat("Very Long Sandwich Name ", 5)
+	if _, err := store.AddUserFoodLog(ctx, &database.FoodLog{UserID: alice.ID, FoodItem: foodItem, Calories: 400, Quantity: 1}); err != nil {
+		t.Fatalf("adding food log: %v", err)
+	}
+
+	responder := &discordtest.Responder{}
+	discord.Wrap(ComponentHandlers["flquantity"])(discord.NewContext(ctx, responder, discordtest.Component(alice, discord.Truncate("flquantity_inc_100_1_"+foodItem, 100)), store))
+
+	resp := responder.Last(t)
+	if text := discordtest.EmbedText(resp); !strings.Contains(text, "**Total Consumed**: 800") {
+		t.Errorf("embed is missing the increased quantity, got:\n%v", text)
+	}
+	buttons := discordtest.Buttons(resp)
+	if len(buttons) == 0 {
+		t.Fatal("got no buttons")
+	}
+	for _, button := range buttons {
+		if len(button.CustomID) > 100 || len(button.Label) > 80 {
+			t.Errorf("button %q labelled %q is longer than Discord allows", button.CustomID, button.Label)
+		}
+	}
+}
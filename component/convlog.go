@@ -0,0 +1,61 @@
+package component
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+)
+
+func HandleConvLogAdd(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userDisplayName := c.User.GlobalName
+	// The food name is last so it can safely contain underscores
+	parts := strings.SplitN(c.Interaction.MessageComponentData().CustomID, "_", 5)
+	if len(parts) < 5 {
+		logger.Error("Failed to parse converted food log")
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+	userId := parts[1]
+
+	calories, caloriesErr := strconv.ParseInt(parts[2], 10, 16)
+	perUnit, perUnitErr := strconv.ParseFloat(parts[3], 64)
+	if caloriesErr != nil || perUnitErr != nil {
+		logger.Error("Failed to parse converted food log")
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	foodLog := database.FoodLog{
+		UserID:   userId,
+		FoodItem: parts[4],
+		Calories: int16(calories),
+		Quantity: 1,
+	}
+
+	id, addFoodLogErr := helper.AddFoodLogAndUpdateStreak(c, c.Store, c.Account, &foodLog)
+	if addFoodLogErr != nil {
+		logger.Error("Error adding converted food log", "error", addFoodLogErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	savedFood := database.SavedFood{
+		UserID:          userId,
+		Name:            foodLog.FoodItem,
+		CaloriesPerUnit: perUnit,
+	}
+	if saveErr := c.Store.SaveUserFood(c, &savedFood); saveErr != nil {
+		logger.Error("Error saving food", logging.FoodKey, foodLog.FoodItem, "error", saveErr)
+	}
+
+	messageComponents := helper.CreateAddRemoveUpdateButtons(userId, id, foodLog.FoodItem)
+
+	logger.Info("Added converted food log", "log_id", id)
+	helper.DisplayFoodLogEmbed(c, userId, userDisplayName, time.Now(), messageComponents, true)
+}
@@ -2,41 +2,43 @@ package component
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/discordcalorietracker/database"
 	"github.com/discordcalorietracker/discord"
 	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
 )
 
-func HandleDeleteLog(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userDisplayName := i.Member.User.GlobalName
-	parts := strings.Split(i.MessageComponentData().CustomID, "_")
+func HandleDeleteLog(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userDisplayName := c.User.GlobalName
+	parts := strings.Split(c.Interaction.MessageComponentData().CustomID, "_")
 	userId := parts[1]
 
 	logId, parseErr := strconv.ParseInt(parts[2], 10, 16)
 	if parseErr != nil {
-		log.Printf("Failed to parse log ID. Error: %v", parseErr)
+		logger.Error("Failed to parse log ID", "error", parseErr)
 		return
 	}
 
-	n, deleteErr := database.DeleteUserFoodLog(userId, logId)
+	n, deleteErr := c.Store.DeleteUserFoodLog(c, userId, logId)
 	if deleteErr != nil {
-		log.Printf("Error deleting food log for user %v: %v", userDisplayName, deleteErr)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		logger.Error("Error deleting food log", "log_id", logId, "error", deleteErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
 		return
 	}
 
 	if n == 0 {
-		log.Printf("Could not find a food log with ID %v for user %v.", logId, userDisplayName)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse(fmt.Sprintf("Could not find a food log with ID %v.", logId), true, nil))
+		logger.Info("Could not find the food log", "log_id", logId)
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Could not find a food log with ID %v.", logId), true, nil))
 		return
 	}
 
-	log.Printf("Deleted food log %v for user %v.", logId, userDisplayName)
-	helper.DisplayFoodLogEmbed(s, i, userId, userDisplayName, time.Now(), nil, true)
+	messageComponents := []discordgo.MessageComponent{helper.CreateUndoButton(userId, logId)}
+
+	logger.Info("Deleted food log", "log_id", logId)
+	helper.DisplayFoodLogEmbed(c, userId, userDisplayName, time.Now(), messageComponents, true)
 }
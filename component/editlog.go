@@ -0,0 +1,145 @@
+package component
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/units"
+)
+
+// HandleEditLog opens a modal filled in with the entry as it is, which HandleEditLogSubmit saves.
+func HandleEditLog(c *discord.Context) {
+	logger := logging.FromContext(c)
+	parts := strings.Split(c.CustomID(), "_")
+	userId := parts[1]
+
+	logId, parseErr := strconv.ParseInt(parts[2], 10, 64)
+	if parseErr != nil {
+		logger.Error("Failed to parse log ID", "error", parseErr)
+		return
+	}
+
+	foodLog, fetchErr := c.Store.FetchFoodLog(c, userId, logId)
+	if fetchErr != nil {
+		logger.Error("Error fetching food log", "log_id", logId, "error", fetchErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	if foodLog.ID == 0 {
+		logger.Info("Could not find the food log", "log_id", logId)
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Could not find a food log with ID %v.", logId), true, nil))
+		return
+	}
+
+	// Entries logged before names were shortened can be longer than MaxFoodItemLength
+	foodItemLength := max(helper.MaxFoodItemLength, utf8.RuneCountInString(foodLog.FoodItem))
+
+	energyUnit := c.Account.EnergyUnit
+	c.Respond(&discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: fmt.Sprintf("fleditsave_%s_%d", userId, logId),
+			Title:    fmt.Sprintf("Edit entry %d", logId),
+			Components: []discordgo.MessageComponent{
+				editInput("fooditem", "Food item", foodLog.FoodItem, foodItemLength, true),
+				editInput("calories", fmt.Sprintf("Calories per serving, in %s", units.EnergyName(energyUnit)), strconv.FormatFloat(math.Round(units.FromKcal(float64(foodLog.Calories), energyUnit)), 'f', 0, 64), 6, true),
+				editInput("quantity", "Servings, e.g 1.5", helper.FormatQuantity(foodLog.Quantity), 7, true),
+				editInput("serving", "Serving size, e.g slice or 100 g", foodLog.Serving, 20, false),
+			},
+		},
+	})
+}
+
+// editInput is a single line text input on its own row, as modals only allow one input per row.
+func editInput(customID string, label string, value string, maxLength int, required bool) discordgo.ActionsRow {
+	minLength := 0
+	if required {
+		minLength = 1
+	}
+
+	return discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.TextInput{
+				CustomID:  customID,
+				Label:     label,
+				Style:     discordgo.TextInputShort,
+				Value:     value,
+				Required:  required,
+				MinLength: minLength,
+				MaxLength: maxLength,
+			},
+		},
+	}
+}
+
+// HandleEditLogSubmit saves the entry as it was edited in the modal opened by HandleEditLog.
+func HandleEditLogSubmit(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userDisplayName := c.User.GlobalName
+	parts := strings.Split(c.CustomID(), "_")
+	userId := parts[1]
+	energyUnit := c.Account.EnergyUnit
+
+	logId, parseErr := strconv.ParseInt(parts[2], 10, 64)
+	if parseErr != nil {
+		logger.Error("Failed to parse log ID", "error", parseErr)
+		return
+	}
+
+	foodItem := strings.TrimSpace(c.ModalValue("fooditem"))
+	if foodItem == "" {
+		c.Respond(discord.CreateInteractionResponse("The food item can't be empty.", true, nil))
+		return
+	}
+
+	energy, energyErr := strconv.ParseInt(strings.TrimSpace(c.ModalValue("calories")), 10, 64)
+	calories, inRange := helper.ToKcal(energy, energyUnit)
+	if energyErr != nil || !inRange {
+		c.Respond(discord.CreateInteractionResponse(helper.OutOfRangeMessage(energyUnit), true, nil))
+		return
+	}
+
+	parsedQuantity, quantityErr := strconv.ParseFloat(strings.TrimSpace(c.ModalValue("quantity")), 64)
+	quantity, inRange := helper.RoundQuantity(parsedQuantity)
+	if quantityErr != nil || !inRange {
+		c.Respond(discord.CreateInteractionResponse(helper.QuantityOutOfRangeMessage(), true, nil))
+		return
+	}
+
+	foodLog := database.FoodLog{
+		ID:       logId,
+		UserID:   userId,
+		FoodItem: foodItem,
+		Calories: calories,
+		Quantity: quantity,
+		Serving:  strings.TrimSpace(c.ModalValue("serving")),
+	}
+
+	n, updateErr := c.Store.UpdateUserFoodLog(c, &foodLog)
+	if updateErr != nil {
+		logger.Error("Error updating food log", "log_id", logId, "error", updateErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	if n == 0 {
+		logger.Info("Could not find the food log", "log_id", logId)
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Could not find a food log with ID %v.", logId), true, nil))
+		return
+	}
+
+	messageComponents := helper.CreateAddRemoveUpdateButtons(userId, logId, foodLog.FoodItem)
+
+	logger.Info("Edited food log", "log_id", logId)
+	helper.DisplayFoodLogEmbed(c, userId, userDisplayName, time.Now(), messageComponents, true)
+}
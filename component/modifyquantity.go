@@ -2,47 +2,47 @@ package component
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/bwmarrin/discordgo"
-	"github.com/discordcalorietracker/database"
 	"github.com/discordcalorietracker/discord"
 	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
 )
 
-func HandleModifyFoodQuantity(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userDisplayName := i.Member.User.GlobalName
-	parts := strings.Split(i.MessageComponentData().CustomID, "_")
+func HandleModifyFoodQuantity(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userDisplayName := c.User.GlobalName
+	// The food name is last so it can safely contain underscores
+	parts := strings.SplitN(c.Interaction.MessageComponentData().CustomID, "_", 5)
 	direction := parts[1]
 	userId := parts[2]
 
 	parsedId, parseErr := strconv.ParseInt(parts[3], 10, 16)
 	if parseErr != nil {
-		log.Printf("Failed to parse log ID. Error: %v", parseErr)
+		logger.Error("Failed to parse log ID", "error", parseErr)
 		return
 	}
 
 	logId := int64(parsedId)
 	foodName := parts[4]
 
-	n, updateErr := database.UpdateFoodLogQuantity(userId, logId, direction)
+	n, updateErr := c.Store.UpdateFoodLogQuantity(c, userId, logId, direction, helper.QuantityStep)
 	if updateErr != nil {
-		log.Printf("Error updating food log with ID %v for user %v: %v", logId, userDisplayName, updateErr)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		logger.Error("Error updating food log", "log_id", logId, "error", updateErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
 		return
 	}
 
 	if n == 0 {
-		log.Printf("Failed to update food log with ID %v for user %v.", logId, userDisplayName)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse(fmt.Sprintf("Failed to update the quantity for food log with ID %v.", logId), true, nil))
+		logger.Info("Food log quantity was not updated", "log_id", logId)
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Failed to update the quantity for food log with ID %v.", logId), true, nil))
 		return
 	}
 
 	messageComponents := helper.CreateAddRemoveUpdateButtons(userId, logId, foodName)
 
-	log.Printf("Updated the quantity for food log %v for user %v and retrieved remaining calories.", logId, userDisplayName)
-	helper.DisplayFoodLogEmbed(s, i, userId, userDisplayName, time.Now(), messageComponents, true)
+	logger.Info("Updated food log quantity", "log_id", logId)
+	helper.DisplayFoodLogEmbed(c, userId, userDisplayName, time.Now(), messageComponents, true)
 }
@@ -0,0 +1,63 @@
+package component
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+)
+
+func HandleQuickLogAdd(c *discord.Context) {
+	logger := logging.FromContext(c)
+	userDisplayName := c.User.GlobalName
+	// The food name is last so it can safely contain underscores
+	parts := strings.SplitN(c.Interaction.MessageComponentData().CustomID, "_", 7)
+	if len(parts) < 7 {
+		logger.Error("Failed to parse quick log entry")
+		return
+	}
+	userId := parts[1]
+
+	calories, caloriesErr := strconv.ParseInt(parts[2], 10, 16)
+	quantity, quantityErr := strconv.ParseFloat(parts[3], 64)
+	if caloriesErr != nil || quantityErr != nil {
+		logger.Error("Failed to parse quick log entry")
+		return
+	}
+
+	foodLog := database.FoodLog{
+		UserID:   userId,
+		FoodItem: parts[6],
+		Calories: int16(calories),
+		Quantity: quantity,
+		Serving:  parts[5],
+	}
+
+	id, addFoodLogErr := helper.AddFoodLogAndUpdateStreak(c, c.Store, c.Account, &foodLog)
+	if addFoodLogErr != nil {
+		logger.Error("Error adding quick log food log", "error", addFoodLogErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	// Only whole servings are remembered, weighed entries have their amount in the name
+	if weighed := parts[4] == "w"; !weighed {
+		savedFood := database.SavedFood{
+			UserID:   userId,
+			Name:     foodLog.FoodItem,
+			Calories: foodLog.Calories,
+		}
+		if saveErr := c.Store.SaveUserFood(c, &savedFood); saveErr != nil {
+			logger.Error("Error saving food", logging.FoodKey, foodLog.FoodItem, "error", saveErr)
+		}
+	}
+
+	messageComponents := helper.CreateAddRemoveUpdateButtons(userId, id, foodLog.FoodItem)
+
+	logger.Info("Added quick log food log", "log_id", id)
+	helper.DisplayFoodLogEmbed(c, userId, userDisplayName, time.Now(), messageComponents, true)
+}
@@ -0,0 +1,24 @@
+package component
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+)
+
+func HandleUndo(c *discord.Context) {
+	logger := logging.FromContext(c)
+	parts := strings.Split(c.Interaction.MessageComponentData().CustomID, "_")
+	userId := parts[1]
+
+	logId, parseErr := strconv.ParseInt(parts[2], 10, 64)
+	if parseErr != nil {
+		logger.Error("Failed to parse log ID", "error", parseErr)
+		return
+	}
+
+	helper.UndoFoodLogChange(c, userId, logId)
+}
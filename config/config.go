@@ -0,0 +1,336 @@
+package config
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is prepended to the upper cased setting keys to get their environment variables, e.g CALORIEBOT_TOKEN.
+const EnvPrefix = "CALORIEBOT_"
+
+type Config struct {
+	Discord    Discord    `yaml:"discord"`
+	Database   Database   `yaml:"database"`
+	Limits     Limits     `yaml:"limits"`
+	RateLimits RateLimits `yaml:"rate_limits"`
+	Display    Display    `yaml:"display"`
+	HTTP       HTTP       `yaml:"http"`
+	Logging    Logging    `yaml:"logging"`
+	Monitoring Monitoring `yaml:"monitoring"`
+}
+
+type Discord struct {
+	Token string `yaml:"token"`
+	// TokenFile is read for the token when it isn't given directly, e.g a Docker secret
+	TokenFile      string `yaml:"token_file"`
+	GuildID        string `yaml:"guild_id"`
+	RemoveCommands bool   `yaml:"remove_commands"`
+	// AdminChannelID is where handler errors are posted, they are only logged when it is empty
+	AdminChannelID string `yaml:"admin_channel_id"`
+}
+
+type Database struct {
+	DSN string `yaml:"dsn"`
+}
+
+type Limits struct {
+	MaxItemCalories float64 `yaml:"max_item_calories"`
+	MaxAverageDays  float64 `yaml:"max_average_days"`
+	// MaxDailyEntries is the most entries a user can add to their log in a day, 0 for no limit
+	MaxDailyEntries int `yaml:"max_daily_entries"`
+	// QuantityStep is how much the add and remove buttons change an entries quantity by, e.g 0.5
+	QuantityStep float64 `yaml:"quantity_step"`
+}
+
+// RateLimits limits how often each user can use each command or button.
+type RateLimits struct {
+	Default RateLimit `yaml:"default"`
+	// Commands overrides the default by command name or button custom ID prefix, e.g add or flquantity
+	Commands map[string]RateLimit `yaml:"commands"`
+}
+
+// RateLimit allows Burst uses at once, with one more allowed every Per. Zero for either is unlimited.
+type RateLimit struct {
+	Burst int           `yaml:"burst"`
+	Per   time.Duration `yaml:"per"`
+}
+
+type Display struct {
+	// EmbedColour is a hex colour like #89CFF0
+	EmbedColour string `yaml:"embed_colour"`
+	DateFormat  string `yaml:"date_format"`
+}
+
+// HTTP serves Discord's interactions endpoint instead of connecting to the gateway when Listen is set.
+type HTTP struct {
+	Listen string `yaml:"listen"`
+	// PublicKey is the applications hex encoded public key from the developer portal, used to verify requests
+	PublicKey string `yaml:"public_key"`
+}
+
+type Logging struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level"`
+	// Format is text or json
+	Format string `yaml:"format"`
+	// Redact hides user IDs, names and food in the logs
+	Redact bool `yaml:"redact"`
+}
+
+// Monitoring serves metrics and health checks when Listen is set.
+type Monitoring struct {
+	Listen string `yaml:"listen"`
+}
+
+// Default returns the settings used when nothing else is configured.
+func Default() Config {
+	return Config{
+		Database: Database{
+			DSN: "app.db",
+		},
+		Limits: Limits{
+			MaxItemCalories: 5000,
+			MaxAverageDays:  7,
+			MaxDailyEntries: 200,
+			QuantityStep:    1,
+		},
+		RateLimits: RateLimits{
+			Default: RateLimit{Burst: 10, Per: 2 * time.Second},
+			Commands: map[string]RateLimit{
+				"add":        {Burst: 5, Per: 5 * time.Second},
+				"flquantity": {Burst: 10, Per: time.Second},
+			},
+		},
+		Display: Display{
+			EmbedColour: "#89CFF0",
+			DateFormat:  "02/01/2006",
+		},
+		Logging: Logging{
+			Level:  "info",
+			Format: "text",
+			Redact: true,
+		},
+	}
+}
+
+// Load reads the defaults, then the YAML file at path if one is given or set in CALORIEBOT_CONFIG, then the environment variables.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv(EnvPrefix + "CONFIG")
+	}
+
+	if path != "" {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, err
+		}
+
+		// Unknown keys are rejected so typos don't silently fall back to the defaults
+		decoder := yaml.NewDecoder(bytes.NewReader(contents))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, fmt.Errorf("parsing %v: %w", path, err)
+		}
+	}
+
+	for _, key := range Keys {
+		if value, ok := os.LookupEnv(EnvPrefix + strings.ToUpper(key)); ok {
+			if err := cfg.Set(key, value); err != nil {
+				return cfg, fmt.Errorf("%v%v: %w", EnvPrefix, strings.ToUpper(key), err)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// Keys are the settings that can be overridden by environment variables and flags.
+var Keys = []string{
+	"token",
+	"token_file",
+	"guild_id",
+	"remove_commands",
+	"admin_channel_id",
+	"db",
+	"max_item_calories",
+	"max_average_days",
+	"max_daily_entries",
+	"quantity_step",
+	"rate_limit_burst",
+	"rate_limit_per",
+	"embed_colour",
+	"date_format",
+	"http_listen",
+	"public_key",
+	"log_level",
+	"log_format",
+	"log_redact",
+	"monitoring_listen",
+}
+
+// Set overrides a single setting from its string form.
+func (c *Config) Set(key string, value string) error {
+	var err error
+	switch key {
+	case "token":
+		// Whichever of the token and token file is set last wins
+		c.Discord.Token, c.Discord.TokenFile = value, ""
+	case "token_file":
+		c.Discord.Token, c.Discord.TokenFile = "", value
+	case "guild_id":
+		c.Discord.GuildID = value
+	case "remove_commands":
+		c.Discord.RemoveCommands, err = strconv.ParseBool(value)
+	case "admin_channel_id":
+		c.Discord.AdminChannelID = value
+	case "db":
+		c.Database.DSN = value
+	case "max_item_calories":
+		c.Limits.MaxItemCalories, err = strconv.ParseFloat(value, 64)
+	case "max_average_days":
+		c.Limits.MaxAverageDays, err = strconv.ParseFloat(value, 64)
+	case "max_daily_entries":
+		c.Limits.MaxDailyEntries, err = strconv.Atoi(value)
+	case "quantity_step":
+		c.Limits.QuantityStep, err = strconv.ParseFloat(value, 64)
+	case "rate_limit_burst":
+		c.RateLimits.Default.Burst, err = strconv.Atoi(value)
+	case "rate_limit_per":
+		c.RateLimits.Default.Per, err = time.ParseDuration(value)
+	case "embed_colour":
+		c.Display.EmbedColour = value
+	case "date_format":
+		c.Display.DateFormat = value
+	case "http_listen":
+		c.HTTP.Listen = value
+	case "public_key":
+		c.HTTP.PublicKey = value
+	case "log_level":
+		c.Logging.Level = value
+	case "log_format":
+		c.Logging.Format = value
+	case "log_redact":
+		c.Logging.Redact, err = strconv.ParseBool(value)
+	case "monitoring_listen":
+		c.Monitoring.Listen = value
+	default:
+		return fmt.Errorf("unknown setting %v", key)
+	}
+	return err
+}
+
+// Validate checks the settings are usable and reads the token file, if there is one, into the token.
+func (c *Config) Validate() error {
+	if c.Discord.TokenFile != "" {
+		token, err := os.ReadFile(c.Discord.TokenFile)
+		if err != nil {
+			return fmt.Errorf("reading token file: %w", err)
+		}
+		c.Discord.Token = strings.TrimSpace(string(token))
+	}
+
+	if c.Database.DSN == "" {
+		return errors.New("database dsn must not be empty")
+	}
+
+	// Calories are stored as 16 bit integers
+	if c.Limits.MaxItemCalories < 1 || c.Limits.MaxItemCalories > math.MaxInt16 {
+		return fmt.Errorf("max_item_calories must be between 1 and %d", math.MaxInt16)
+	}
+	if c.Limits.MaxAverageDays < 2 || c.Limits.MaxAverageDays > 365 || c.Limits.MaxAverageDays != math.Trunc(c.Limits.MaxAverageDays) {
+		return errors.New("max_average_days must be a whole number between 2 and 365")
+	}
+	if c.Limits.MaxDailyEntries < 0 {
+		return errors.New("max_daily_entries can't be negative, use 0 for no limit")
+	}
+	// Quantities are stored to two decimal places
+	if c.Limits.QuantityStep < 0.01 || c.Limits.QuantityStep > 100 || math.Abs(c.Limits.QuantityStep*100-math.Round(c.Limits.QuantityStep*100)) > 1e-9 {
+		return errors.New("quantity_step must be between 0.01 and 100 with at most two decimal places")
+	}
+	for name, limit := range c.RateLimits.Commands {
+		if limit.Burst < 0 || limit.Per < 0 {
+			return fmt.Errorf("rate limit for %v must not be negative", name)
+		}
+	}
+	if c.RateLimits.Default.Burst < 0 || c.RateLimits.Default.Per < 0 {
+		return errors.New("default rate limit must not be negative")
+	}
+
+	if _, err := c.Display.Colour(); err != nil {
+		return err
+	}
+
+	// Dates end up in button IDs split on underscores, and have to parse back to the same day for /list
+	format := c.Display.DateFormat
+	sample := time.Date(2023, time.December, 31, 0, 0, 0, 0, time.UTC)
+	if format == "" || strings.Contains(format, "_") {
+		return errors.New("date_format must be set and not contain underscores")
+	}
+	if parsed, err := time.Parse(format, sample.Format(format)); err != nil || !parsed.Equal(sample) {
+		return fmt.Errorf("date_format %q must include the day, month and year", format)
+	}
+
+	if c.HTTP.Listen != "" {
+		if _, err := c.HTTP.Key(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := c.Logging.SlogLevel(); err != nil {
+		return err
+	}
+	if c.Logging.Format != "text" && c.Logging.Format != "json" {
+		return fmt.Errorf("log format %q must be text or json", c.Logging.Format)
+	}
+
+	return nil
+}
+
+// RequireToken checks a bot token was configured, which is only needed to connect to Discord.
+func (d Discord) RequireToken() error {
+	if d.Token == "" {
+		return fmt.Errorf("no bot token, pass -token or -token-file or set %vTOKEN or %vTOKEN_FILE", EnvPrefix, EnvPrefix)
+	}
+	return nil
+}
+
+// Colour converts the hex embed colour into the number Discord expects.
+func (d Display) Colour() (int, error) {
+	colour, err := strconv.ParseUint(strings.TrimPrefix(d.EmbedColour, "#"), 16, 32)
+	if err != nil || colour > 0xFFFFFF {
+		return 0, fmt.Errorf("embed_colour %q must be a hex colour like #89CFF0", d.EmbedColour)
+	}
+	return int(colour), nil
+}
+
+// Key decodes the public key Discord signs interaction requests with.
+func (h HTTP) Key() (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(h.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("public_key must be the hex encoded public key of the application")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// SlogLevel parses the log level.
+func (l Logging) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return level, fmt.Errorf("log level %q must be debug, info, warn or error", l.Level)
+	}
+	return level, nil
+}
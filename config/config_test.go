@@ -0,0 +1,139 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		wantErr string
+	}{
+		{name: "defaults", change: func(c *Config) {}},
+		{name: "invalid log level", change: func(c *Config) { c.Logging.Level = "verbose" }, wantErr: "log level"},
+		{name: "invalid log format", change: func(c *Config) { c.Logging.Format = "xml" }, wantErr: "log format"},
+		{name: "empty database", change: func(c *Config) { c.Database.DSN = "" }, wantErr: "dsn"},
+		{name: "date format without the year", change: func(c *Config) { c.Display.DateFormat = "02/01" }, wantErr: "date_format"},
+		{name: "missing token file", change: func(c *Config) { c.Discord.TokenFile = filepath.Join(t.TempDir(), "missing") }, wantErr: "token file"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := Default()
+			test.change(&cfg)
+			err := cfg.Validate()
+			if test.wantErr == "" && err != nil {
+				t.Fatalf("Validate() = %v, want no error", err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Fatalf("Validate() = %v, want an error about %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestSlogLevel(t *testing.T) {
+	tests := []struct {
+		level   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{level: "debug", want: slog.LevelDebug},
+		{level: "INFO", want: slog.LevelInfo},
+		{level: "warn", want: slog.LevelWarn},
+		{level: "error", want: slog.LevelError},
+		{level: "verbose", wantErr: true},
+		{level: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		level, err := Logging{Level: test.level}.SlogLevel()
+		if (err != nil) != test.wantErr {
+			t.Errorf("SlogLevel(%q) error = %v, want error %v", test.level, err, test.wantErr)
+		} else if err == nil && level != test.want {
+			t.Errorf("SlogLevel(%q) = %v, want %v", test.level, level, test.want)
+		}
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Discord.RequireToken(); err == nil {
+		t.Errorf("RequireToken() with no token = nil, want an error")
+	}
+
+	// The token file is read into the token by Validate
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Set("token_file", path)
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Discord.RequireToken(); err != nil || cfg.Discord.Token != "secret" {
+		t.Errorf("token = %q, %v, want the contents of the token file", cfg.Discord.Token, err)
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	contents := `
+discord:
+  token: from-file
+  guild_id: "1"
+logging:
+  level: debug
+  format: json
+rate_limits:
+  default:
+    burst: 3
+    per: 1s
+`
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvPrefix+"TOKEN", "from-env")
+	t.Setenv(EnvPrefix+"LOG_LEVEL", "warn")
+	t.Setenv(EnvPrefix+"RATE_LIMIT_PER", "5s")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Discord.Token != "from-env" || cfg.Logging.Level != "warn" || cfg.RateLimits.Default.Per != 5*time.Second {
+		t.Errorf("got token %q, level %q and rate limit per %v, want the environment values", cfg.Discord.Token, cfg.Logging.Level, cfg.RateLimits.Default.Per)
+	}
+	// Settings without an environment variable keep the file values
+	if cfg.Discord.GuildID != "1" || cfg.Logging.Format != "json" || cfg.RateLimits.Default.Burst != 3 {
+		t.Errorf("got guild %q, format %q and burst %d, want the file values", cfg.Discord.GuildID, cfg.Logging.Format, cfg.RateLimits.Default.Burst)
+	}
+	// And the defaults fill in what neither sets
+	if cfg.Database.DSN != Default().Database.DSN {
+		t.Errorf("dsn = %q, want the default", cfg.Database.DSN)
+	}
+}
+
+func TestLoadRejectsUnknownKeysAndBadValues(t *testing.T) {
+	dir := t.TempDir()
+	unknown := filepath.Join(dir, "unknown.yaml")
+	if err := os.WriteFile(unknown, []byte("logging:\n  levle: debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(unknown); err == nil {
+		t.Errorf("Load() with a misspelt key = nil, want an error")
+	}
+
+	t.Setenv(EnvPrefix+"CONFIG", "")
+	t.Setenv(EnvPrefix+"MAX_DAILY_ENTRIES", "lots")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), EnvPrefix+"MAX_DAILY_ENTRIES") {
+		t.Errorf("Load() with a bad environment value = %v, want an error naming the variable", err)
+	}
+}
@@ -0,0 +1,314 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Actions recorded in the food log audit
+const (
+	AuditCreate   = "create"
+	AuditUpdate   = "update"
+	AuditQuantity = "quantity"
+	AuditDelete   = "delete"
+	AuditRestore  = "restore"
+	AuditUndo     = "undo"
+)
+
+// ErrChangedSince is returned when undoing a change to a food log that no longer looks like the change left it.
+var ErrChangedSince = errors.New("food log changed since")
+
+// FoodLogAudit is a single change to a food log. Before is nil for creates and restores, and After is nil for deletes, as
+// logs in the trash are treated as gone.
+type FoodLogAudit struct {
+	ID        int64
+	LogID     int64
+	UserID    string
+	Action    string
+	Before    *FoodLog
+	After     *FoodLog
+	Undone    bool
+	ChangedAt time.Time
+}
+
+const auditColumns = `id, log_id, user_id, action,
+	before_food_item, before_calories, before_quantity, before_serving, before_date_time,
+	after_food_item, after_calories, after_quantity, after_serving, after_date_time,
+	undone, changed_at`
+
+// auditor records changes to food logs in the food_log_audit table and undoes them, shared by both stores.
+type auditor struct {
+	db *sql.DB
+	// bind rewrites ? placeholders into the drivers placeholder style
+	bind func(query string) string
+}
+
+// audit runs change in a transaction, recording the food log as it was before and after when change affected it.
+// change returns the ID of the log it changed, which for creates is only known once it has run, and the rows affected.
+func (a *auditor) audit(ctx context.Context, action string, userId string, logId int64, change func(tx *sql.Tx) (int64, int64, error)) (int64, int64, error) {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var before *FoodLog
+	if logId != 0 {
+		if before, err = a.fetchFoodLog(ctx, tx, userId, logId); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	logId, n, err := change(tx)
+	if err != nil || n == 0 {
+		return logId, n, err
+	}
+
+	after, err := a.fetchFoodLog(ctx, tx, userId, logId)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := a.record(ctx, tx, action, userId, logId, before, after); err != nil {
+		return 0, 0, err
+	}
+
+	return logId, n, tx.Commit()
+}
+
+// fetchFoodLog returns nil when the user has no food log with the ID outside the trash.
+func (a *auditor) fetchFoodLog(ctx context.Context, tx *sql.Tx, userId string, logId int64) (*FoodLog, error) {
+	var foodLog FoodLog
+	row := tx.QueryRowContext(
+		ctx,
+		a.bind(`SELECT id, user_id, food_item, calories, quantity, serving, date_time FROM food_log WHERE user_id=? AND id=? AND deleted_at IS NULL`),
+		userId, logId,
+	)
+	err := row.Scan(&foodLog.ID, &foodLog.UserID, &foodLog.FoodItem, &foodLog.Calories, &foodLog.Quantity, &foodLog.Serving, &foodLog.DateTime)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &foodLog, nil
+}
+
+func (a *auditor) record(ctx context.Context, tx *sql.Tx, action string, userId string, logId int64, before *FoodLog, after *FoodLog) error {
+	args := append([]any{logId, userId, action}, auditValues(before)...)
+	args = append(args, auditValues(after)...)
+	_, err := tx.ExecContext(
+		ctx,
+		a.bind(`INSERT INTO food_log_audit (log_id, user_id, action,
+			before_food_item, before_calories, before_quantity, before_serving, before_date_time,
+			after_food_item, after_calories, after_quantity, after_serving, after_date_time)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		args...,
+	)
+	return err
+}
+
+// auditValues are the columns recorded for one side of a change, all NULL when there is no food log on that side.
+func auditValues(foodLog *FoodLog) []any {
+	if foodLog == nil {
+		return []any{nil, nil, nil, nil, nil}
+	}
+	return []any{foodLog.FoodItem, foodLog.Calories, foodLog.Quantity, foodLog.Serving, formatDateTime(foodLog.DateTime)}
+}
+
+// formatDateTime writes times the way both databases store CURRENT_TIMESTAMP, in UTC.
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAudit(row scanner) (FoodLogAudit, error) {
+	var change FoodLogAudit
+	var before, after auditSide
+	err := row.Scan(
+		&change.ID, &change.LogID, &change.UserID, &change.Action,
+		&before.foodItem, &before.calories, &before.quantity, &before.serving, &before.dateTime,
+		&after.foodItem, &after.calories, &after.quantity, &after.serving, &after.dateTime,
+		&change.Undone, &change.ChangedAt,
+	)
+	if err != nil {
+		return change, err
+	}
+
+	change.Before = before.foodLog(change)
+	change.After = after.foodLog(change)
+	return change, nil
+}
+
+// auditSide holds the nullable columns for one side of a change while it is scanned.
+type auditSide struct {
+	foodItem sql.NullString
+	calories sql.NullInt16
+	quantity sql.NullFloat64
+	serving  sql.NullString
+	dateTime sql.NullTime
+}
+
+func (side auditSide) foodLog(change FoodLogAudit) *FoodLog {
+	if !side.foodItem.Valid {
+		return nil
+	}
+	return &FoodLog{
+		ID:       change.LogID,
+		UserID:   change.UserID,
+		FoodItem: side.foodItem.String,
+		Calories: side.calories.Int16,
+		Quantity: side.quantity.Float64,
+		Serving:  side.serving.String,
+		DateTime: side.dateTime.Time,
+	}
+}
+
+// FetchFoodLogHistory returns every change to the users food log, oldest first.
+func (a *auditor) FetchFoodLogHistory(ctx context.Context, userId string, logId int64) ([]FoodLogAudit, error) {
+	rows, err := a.db.QueryContext(
+		ctx,
+		a.bind(`SELECT `+auditColumns+` FROM food_log_audit WHERE user_id=? AND log_id=? ORDER BY id`),
+		userId, logId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []FoodLogAudit
+	for rows.Next() {
+		change, err := scanAudit(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, change)
+	}
+	return history, rows.Err()
+}
+
+// UndoFoodLogChange reverts the users latest change to the food log, or to any of their food logs when logId is 0, and returns it.
+// The returned change has no ID when there is nothing left to undo. ErrChangedSince is returned along with the change when the
+// food log no longer matches what the change left behind.
+func (a *auditor) UndoFoodLogChange(ctx context.Context, userId string, logId int64) (FoodLogAudit, error) {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FoodLogAudit{}, err
+	}
+	defer tx.Rollback()
+
+	// Changes to food logs deleted from the trash before their changes were marked as undone can't be undone either
+	query := `SELECT ` + auditColumns + ` FROM food_log_audit WHERE user_id=? AND action<>? AND undone=FALSE
+		AND log_id IN (SELECT id FROM food_log WHERE user_id=?)`
+	args := []any{userId, AuditUndo, userId}
+	if logId != 0 {
+		query += ` AND log_id=?`
+		args = append(args, logId)
+	}
+	change, err := scanAudit(tx.QueryRowContext(ctx, a.bind(query+` ORDER BY id DESC LIMIT 1`), args...))
+	if err == sql.ErrNoRows {
+		return FoodLogAudit{}, nil
+	}
+	if err != nil {
+		return FoodLogAudit{}, err
+	}
+
+	current, err := a.fetchFoodLog(ctx, tx, userId, change.LogID)
+	if err != nil {
+		return change, err
+	}
+	if !sameEntry(current, change.After) {
+		return change, ErrChangedSince
+	}
+
+	restore := change.Before
+	switch {
+	case restore == nil:
+		_, err = tx.ExecContext(
+			ctx,
+			a.bind(`UPDATE food_log SET deleted_at=? WHERE user_id=? AND id=? AND deleted_at IS NULL`),
+			formatDateTime(time.Now()), userId, change.LogID,
+		)
+	case current == nil:
+		err = a.restoreFoodLog(ctx, tx, restore)
+	default:
+		_, err = tx.ExecContext(
+			ctx,
+			a.bind(`UPDATE food_log SET food_item=?, calories=?, quantity=?, serving=?, date_time=? WHERE user_id=? AND id=?`),
+			restore.FoodItem, restore.Calories, restore.Quantity, restore.Serving, formatDateTime(restore.DateTime), userId, change.LogID,
+		)
+	}
+	if err != nil {
+		return change, err
+	}
+
+	if _, err := tx.ExecContext(ctx, a.bind(`UPDATE food_log_audit SET undone=TRUE WHERE id=?`), change.ID); err != nil {
+		return change, err
+	}
+	if err := a.record(ctx, tx, AuditUndo, userId, change.LogID, current, restore); err != nil {
+		return change, err
+	}
+
+	change.Undone = true
+	return change, tx.Commit()
+}
+
+// restoreFoodLog takes the food log back out of the trash as it was. Food logs deleted from the trash are gone for good,
+// so ErrChangedSince is returned for them.
+func (a *auditor) restoreFoodLog(ctx context.Context, tx *sql.Tx, foodLog *FoodLog) error {
+	result, err := tx.ExecContext(
+		ctx,
+		a.bind(`UPDATE food_log SET food_item=?, calories=?, quantity=?, serving=?, date_time=?, deleted_at=NULL WHERE user_id=? AND id=?`),
+		foodLog.FoodItem, foodLog.Calories, foodLog.Quantity, foodLog.Serving, formatDateTime(foodLog.DateTime), foodLog.UserID, foodLog.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err == nil && n == 0 {
+		err = ErrChangedSince
+	}
+	return err
+}
+
+// deleteTrashed permanently deletes the food logs matching where, marking their changes as undone in the same
+// transaction so undo can't bring them back.
+func (a *auditor) deleteTrashed(ctx context.Context, where string, args ...any) (int64, error) {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(
+		ctx,
+		a.bind(`UPDATE food_log_audit SET undone=TRUE WHERE undone=FALSE AND log_id IN (SELECT id FROM food_log WHERE `+where+`)`),
+		args...,
+	); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, a.bind(`DELETE FROM food_log WHERE `+where), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// sameEntry compares the parts of two food logs a user can see, either of which may be nil.
+func sameEntry(a *FoodLog, b *FoodLog) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.FoodItem == b.FoodItem && a.Calories == b.Calories && a.Quantity == b.Quantity && a.Serving == b.Serving
+}
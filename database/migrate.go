@@ -0,0 +1,215 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/discordcalorietracker/logging"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// migrator applies the numbered migrations in one of the migrations directories, recording progress in the schema_version table.
+type migrator struct {
+	db  *sql.DB
+	dir string
+	// bind rewrites ? placeholders into the drivers placeholder style
+	bind func(query string) string
+	// baseline works out the version of databases created before migrations existed, it may be nil
+	baseline func(ctx context.Context, migrations []Migration) (int, error)
+}
+
+// Migrations reads the embedded migrations, named like 0001_initial.up.sql and 0001_initial.down.sql, in version order.
+func (m *migrator) Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir(path.Join("migrations", m.dir))
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		versionStr, rest, ok := strings.Cut(fileName, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %v", fileName)
+		}
+
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %v: %w", fileName, err)
+		}
+
+		contents, err := migrationFiles.ReadFile(path.Join("migrations", m.dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version}
+			byVersion[version] = migration
+		}
+
+		switch {
+		case strings.HasSuffix(rest, ".up.sql"):
+			migration.Name = strings.TrimSuffix(rest, ".up.sql")
+			migration.Up = string(contents)
+		case strings.HasSuffix(rest, ".down.sql"):
+			migration.Down = string(contents)
+		default:
+			return nil, fmt.Errorf("migration %v must end in .up.sql or .down.sql", fileName)
+		}
+	}
+
+	var migrations []Migration
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d is missing its up or down file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(a, b int) bool {
+		return migrations[a].Version < migrations[b].Version
+	})
+
+	return migrations, nil
+}
+
+// SchemaVersion returns the version of the last applied migration, creating the version table if needed.
+func (m *migrator) SchemaVersion(ctx context.Context) (int, error) {
+	_, err := m.db.ExecContext(
+		ctx,
+		`CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	row := m.db.QueryRowContext(
+		ctx,
+		`SELECT COALESCE(MAX(version), 0) FROM schema_version`,
+	)
+	if err := row.Scan(&version); err != nil {
+		return 0, err
+	}
+
+	if version > 0 || m.baseline == nil {
+		return version, nil
+	}
+
+	migrations, err := m.Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	version, err = m.baseline(ctx, migrations)
+	if err != nil || version == 0 {
+		return version, err
+	}
+
+	for _, migration := range migrations[:version] {
+		_, err := m.db.ExecContext(
+			ctx,
+			m.bind(`INSERT INTO schema_version (version, name) VALUES (?, ?)`),
+			migration.Version, migration.Name,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	logging.FromContext(ctx).Info("Baselined existing database", "version", version)
+	return version, nil
+}
+
+// MigrateUp applies every migration after the current version up to and including target, or all of them when target is 0.
+func (m *migrator) MigrateUp(ctx context.Context, target int) error {
+	current, err := m.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	migrations, err := m.Migrations()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if migration.Version <= current || (target > 0 && migration.Version > target) {
+			continue
+		}
+
+		logging.FromContext(ctx).Info("Applying migration", "version", migration.Version, "name", migration.Name)
+		if err := m.apply(ctx, migration.Up, `INSERT INTO schema_version (version, name) VALUES (?, ?)`, migration.Version, migration.Name); err != nil {
+			return fmt.Errorf("applying migration %d %v: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// MigrateDown reverts the given number of most recently applied migrations.
+func (m *migrator) MigrateDown(ctx context.Context, steps int) error {
+	current, err := m.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	migrations, err := m.Migrations()
+	if err != nil {
+		return err
+	}
+
+	for index := len(migrations) - 1; index >= 0 && steps > 0; index-- {
+		migration := migrations[index]
+		if migration.Version > current {
+			continue
+		}
+
+		logging.FromContext(ctx).Info("Reverting migration", "version", migration.Version, "name", migration.Name)
+		if err := m.apply(ctx, migration.Down, `DELETE FROM schema_version WHERE version=?`, migration.Version); err != nil {
+			return fmt.Errorf("reverting migration %d %v: %w", migration.Version, migration.Name, err)
+		}
+		steps--
+	}
+
+	return nil
+}
+
+// apply runs the migration script and records the change to the version table in a single transaction.
+func (m *migrator) apply(ctx context.Context, script string, versionQuery string, args ...any) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, m.bind(versionQuery), args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,18 @@
+-- "user" is reserved in PostgreSQL so the table is named users
+CREATE TABLE users (
+	id TEXT PRIMARY KEY,
+	daily_calories INTEGER NOT NULL,
+	day_streak INTEGER NOT NULL DEFAULT 0,
+	last_logged DATE DEFAULT '2000-01-01'
+);
+
+CREATE TABLE food_log (
+	id BIGSERIAL PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(id),
+	food_item TEXT NOT NULL,
+	calories INTEGER NOT NULL,
+	quantity INTEGER NOT NULL,
+	date_time TIMESTAMP DEFAULT timezone('utc', now())
+);
+
+CREATE INDEX food_log_user_date ON food_log (user_id, date_time);
@@ -0,0 +1,2 @@
+DROP TABLE nutrition;
+DROP TABLE saved_food;
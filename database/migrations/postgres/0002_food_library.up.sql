@@ -0,0 +1,15 @@
+CREATE TABLE saved_food (
+	user_id TEXT NOT NULL REFERENCES users(id),
+	name TEXT NOT NULL,
+	calories INTEGER NOT NULL DEFAULT 0,
+	calories_per_unit DOUBLE PRECISION NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, name)
+);
+
+CREATE TABLE nutrition (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	kcal_100g DOUBLE PRECISION NOT NULL,
+	serving_grams DOUBLE PRECISION NOT NULL DEFAULT 0,
+	source TEXT NOT NULL
+);
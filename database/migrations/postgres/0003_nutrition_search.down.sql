@@ -0,0 +1,3 @@
+DROP INDEX nutrition_name_search;
+DROP INDEX nutrition_barcode;
+ALTER TABLE nutrition DROP COLUMN barcode;
@@ -0,0 +1,5 @@
+ALTER TABLE nutrition ADD COLUMN barcode TEXT;
+
+CREATE INDEX nutrition_barcode ON nutrition (barcode);
+
+CREATE INDEX nutrition_name_search ON nutrition USING GIN (to_tsvector('simple', name));
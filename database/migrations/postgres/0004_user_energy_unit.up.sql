@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN energy_unit TEXT NOT NULL DEFAULT 'kcal';
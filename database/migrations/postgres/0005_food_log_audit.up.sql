@@ -0,0 +1,18 @@
+CREATE TABLE food_log_audit (
+	id BIGSERIAL PRIMARY KEY,
+	log_id BIGINT NOT NULL,
+	user_id TEXT NOT NULL REFERENCES users(id),
+	action TEXT NOT NULL,
+	before_food_item TEXT,
+	before_calories INTEGER,
+	before_quantity INTEGER,
+	before_date_time TIMESTAMP,
+	after_food_item TEXT,
+	after_calories INTEGER,
+	after_quantity INTEGER,
+	after_date_time TIMESTAMP,
+	undone BOOLEAN NOT NULL DEFAULT FALSE,
+	changed_at TIMESTAMP DEFAULT timezone('utc', now())
+);
+
+CREATE INDEX food_log_audit_user_log ON food_log_audit (user_id, log_id);
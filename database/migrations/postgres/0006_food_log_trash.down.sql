@@ -0,0 +1,2 @@
+DROP INDEX food_log_deleted_at;
+ALTER TABLE food_log DROP COLUMN deleted_at;
@@ -0,0 +1,6 @@
+ALTER TABLE food_log_audit DROP COLUMN after_serving;
+ALTER TABLE food_log_audit DROP COLUMN before_serving;
+ALTER TABLE food_log_audit ALTER COLUMN after_quantity TYPE INTEGER USING ROUND(after_quantity);
+ALTER TABLE food_log_audit ALTER COLUMN before_quantity TYPE INTEGER USING ROUND(before_quantity);
+ALTER TABLE food_log DROP COLUMN serving;
+ALTER TABLE food_log ALTER COLUMN quantity TYPE INTEGER USING GREATEST(ROUND(quantity), 1);
@@ -0,0 +1,6 @@
+ALTER TABLE food_log ALTER COLUMN quantity TYPE NUMERIC(8, 2);
+ALTER TABLE food_log ADD COLUMN serving TEXT NOT NULL DEFAULT '';
+ALTER TABLE food_log_audit ALTER COLUMN before_quantity TYPE NUMERIC(8, 2);
+ALTER TABLE food_log_audit ALTER COLUMN after_quantity TYPE NUMERIC(8, 2);
+ALTER TABLE food_log_audit ADD COLUMN before_serving TEXT;
+ALTER TABLE food_log_audit ADD COLUMN after_serving TEXT;
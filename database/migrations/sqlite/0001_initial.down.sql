@@ -0,0 +1,2 @@
+DROP TABLE food_log;
+DROP TABLE user;
@@ -0,0 +1,16 @@
+CREATE TABLE user (
+	id TEXT PRIMARY KEY,
+	daily_calories INTEGER NOT NULL,
+	day_streak INTEGER NOT NULL DEFAULT 0,
+	last_logged DATE DEFAULT '2000-01-01'
+);
+
+CREATE TABLE food_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id TEXT NOT NULL,
+	food_item TEXT NOT NULL,
+	calories INTEGER NOT NULL,
+	quantity INTEGER NOT NULL,
+	date_time DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES user(id)
+);
@@ -0,0 +1,16 @@
+CREATE TABLE saved_food (
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	calories INTEGER NOT NULL DEFAULT 0,
+	calories_per_unit REAL NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, name),
+	FOREIGN KEY (user_id) REFERENCES user(id)
+);
+
+CREATE TABLE nutrition (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	kcal_100g REAL NOT NULL,
+	serving_grams REAL NOT NULL DEFAULT 0,
+	source TEXT NOT NULL
+);
@@ -0,0 +1,6 @@
+DROP TRIGGER nutrition_fts_update;
+DROP TRIGGER nutrition_fts_delete;
+DROP TRIGGER nutrition_fts_insert;
+DROP TABLE nutrition_fts;
+DROP INDEX nutrition_barcode;
+ALTER TABLE nutrition DROP COLUMN barcode;
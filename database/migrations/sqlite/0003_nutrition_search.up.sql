@@ -0,0 +1,20 @@
+ALTER TABLE nutrition ADD COLUMN barcode TEXT;
+
+CREATE INDEX nutrition_barcode ON nutrition (barcode);
+
+CREATE VIRTUAL TABLE nutrition_fts USING fts5(name, content='nutrition', content_rowid='id');
+
+CREATE TRIGGER nutrition_fts_insert AFTER INSERT ON nutrition BEGIN
+	INSERT INTO nutrition_fts (rowid, name) VALUES (new.id, new.name);
+END;
+
+CREATE TRIGGER nutrition_fts_delete AFTER DELETE ON nutrition BEGIN
+	INSERT INTO nutrition_fts (nutrition_fts, rowid, name) VALUES ('delete', old.id, old.name);
+END;
+
+CREATE TRIGGER nutrition_fts_update AFTER UPDATE ON nutrition BEGIN
+	INSERT INTO nutrition_fts (nutrition_fts, rowid, name) VALUES ('delete', old.id, old.name);
+	INSERT INTO nutrition_fts (rowid, name) VALUES (new.id, new.name);
+END;
+
+INSERT INTO nutrition_fts (nutrition_fts) VALUES ('rebuild');
@@ -0,0 +1 @@
+ALTER TABLE user ADD COLUMN energy_unit TEXT NOT NULL DEFAULT 'kcal';
@@ -0,0 +1 @@
+DROP TABLE food_log_audit;
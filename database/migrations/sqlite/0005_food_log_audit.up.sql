@@ -0,0 +1,19 @@
+CREATE TABLE food_log_audit (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	log_id INTEGER NOT NULL,
+	user_id TEXT NOT NULL,
+	action TEXT NOT NULL,
+	before_food_item TEXT,
+	before_calories INTEGER,
+	before_quantity INTEGER,
+	before_date_time DATETIME,
+	after_food_item TEXT,
+	after_calories INTEGER,
+	after_quantity INTEGER,
+	after_date_time DATETIME,
+	undone BOOLEAN NOT NULL DEFAULT FALSE,
+	changed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES user(id)
+);
+
+CREATE INDEX food_log_audit_user_log ON food_log_audit (user_id, log_id);
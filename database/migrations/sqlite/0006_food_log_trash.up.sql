@@ -0,0 +1,3 @@
+ALTER TABLE food_log ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX food_log_deleted_at ON food_log (deleted_at);
@@ -0,0 +1,4 @@
+UPDATE food_log SET quantity=MAX(ROUND(quantity), 1);
+ALTER TABLE food_log_audit DROP COLUMN after_serving;
+ALTER TABLE food_log_audit DROP COLUMN before_serving;
+ALTER TABLE food_log DROP COLUMN serving;
@@ -0,0 +1,4 @@
+-- quantity keeps its INTEGER affinity, SQLite stores values with a fraction such as 1.5 as REAL in it
+ALTER TABLE food_log ADD COLUMN serving TEXT NOT NULL DEFAULT '';
+ALTER TABLE food_log_audit ADD COLUMN before_serving TEXT;
+ALTER TABLE food_log_audit ADD COLUMN after_serving TEXT;
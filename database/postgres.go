@@ -0,0 +1,645 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/discordcalorietracker/logging"
+)
+
+// Dates are kept in UTC to match SQLite, where CURRENT_TIMESTAMP and CURRENT_DATE are always UTC
+const postgresToday = `timezone('utc', now())::date`
+
+type postgresStore struct {
+	*migrator
+	*auditor
+	db *sql.DB
+}
+
+func openPostgres(dsn string) (*postgresStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &postgresStore{db: db}
+	store.migrator = &migrator{
+		db:   db,
+		dir:  "postgres",
+		bind: bindPostgres,
+	}
+	store.auditor = &auditor{db: db, bind: bindPostgres}
+	return store, nil
+}
+
+// bindPostgres rewrites ? placeholders into PostgreSQL's numbered $1 style.
+func bindPostgres(query string) string {
+	var bound strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			bound.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		bound.WriteRune(r)
+	}
+	return bound.String()
+}
+
+// postgresSearchQuery turns user input into a tsquery matching every word as a prefix.
+func postgresSearchQuery(text string) string {
+	var terms []string
+	for _, word := range searchTerms(text) {
+		terms = append(terms, word+":*")
+	}
+	return strings.Join(terms, " & ")
+}
+
+func (store *postgresStore) Close() error {
+	return store.db.Close()
+}
+
+func (store *postgresStore) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
+
+func (store *postgresStore) FetchUserByID(ctx context.Context, id string) (User, error) {
+	var user User
+
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT id, daily_calories, day_streak, last_logged, energy_unit FROM users WHERE id=$1`, id,
+	)
+
+	err := row.Scan(&user.ID, &user.DailyCalories, &user.DayStreak, &user.LastLogged, &user.EnergyUnit)
+
+	if err != nil && err != sql.ErrNoRows {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// RegisterUser creates the user without daily calories if they don't exist yet, then returns them.
+func (store *postgresStore) RegisterUser(ctx context.Context, id string) (User, error) {
+	_, err := store.db.ExecContext(
+		ctx,
+		`INSERT INTO users (id, daily_calories) VALUES ($1, 0) ON CONFLICT (id) DO NOTHING`, id,
+	)
+	if err != nil {
+		return User{}, err
+	}
+
+	return store.FetchUserByID(ctx, id)
+}
+
+func (store *postgresStore) SetUserCalories(ctx context.Context, user *User) error {
+	logging.FromContext(ctx).Debug("Setting the calories in the database")
+	_, err := store.db.ExecContext(
+		ctx,
+		`INSERT INTO users (id, daily_calories) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET daily_calories=excluded.daily_calories`,
+		user.ID, user.DailyCalories,
+	)
+	return err
+}
+
+func (store *postgresStore) SetUserEnergyUnit(ctx context.Context, userId string, energyUnit string) (int64, error) {
+	result, err := store.db.ExecContext(
+		ctx,
+		`UPDATE users SET energy_unit=$1 WHERE id=$2`,
+		energyUnit, userId,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (store *postgresStore) UpdateUserStreak(ctx context.Context, userId string) (int64, error) {
+	result, err := store.db.ExecContext(
+		ctx,
+		`UPDATE users SET day_streak = CASE WHEN last_logged = `+postgresToday+` - 1 THEN day_streak + 1 ELSE 1 END, last_logged = `+postgresToday+` WHERE id = $1`,
+		userId,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (store *postgresStore) AddUserFoodLog(ctx context.Context, foodLog *FoodLog) (int64, error) {
+	logging.FromContext(ctx).Debug("Adding a food log to the database")
+	id, _, err := store.audit(ctx, AuditCreate, foodLog.UserID, 0, func(tx *sql.Tx) (int64, int64, error) {
+		row := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO food_log (user_id, food_item, calories, quantity, serving) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			foodLog.UserID, foodLog.FoodItem, foodLog.Calories, foodLog.Quantity, foodLog.Serving,
+		)
+
+		var id int64
+		err := row.Scan(&id)
+		return id, 1, err
+	})
+	return id, err
+}
+
+func (store *postgresStore) UpdateUserFoodLog(ctx context.Context, foodLog *FoodLog) (int64, error) {
+	_, n, err := store.audit(ctx, AuditUpdate, foodLog.UserID, foodLog.ID, func(tx *sql.Tx) (int64, int64, error) {
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE food_log SET food_item=$1, calories=$2, quantity=$3, serving=$4 WHERE id=$5 AND user_id=$6 AND deleted_at IS NULL`,
+			foodLog.FoodItem, foodLog.Calories, foodLog.Quantity, foodLog.Serving, foodLog.ID, foodLog.UserID,
+		)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n, err := result.RowsAffected()
+		return foodLog.ID, n, err
+	})
+	return n, err
+}
+
+// UpdateFoodLogQuantity adds or takes the step from the quantity, which can't go down to 0 or below.
+func (store *postgresStore) UpdateFoodLogQuantity(ctx context.Context, userId string, logId int64, direction string, step float64) (int64, error) {
+	var query string
+	args := []any{step, logId, userId}
+	switch direction {
+	case "inc":
+		query = `UPDATE food_log SET quantity=ROUND(quantity+$1::numeric, 2), date_time=timezone('utc', now()) WHERE id=$2 AND user_id=$3 AND deleted_at IS NULL`
+	case "dec":
+		query = `UPDATE food_log SET quantity=ROUND(quantity-$1::numeric, 2), date_time=timezone('utc', now()) WHERE id=$2 AND user_id=$3 AND deleted_at IS NULL AND quantity > $1::numeric`
+	default:
+		return 0, errors.New("invalid direction")
+	}
+
+	_, n, err := store.audit(ctx, AuditQuantity, userId, logId, func(tx *sql.Tx) (int64, int64, error) {
+		result, err := tx.ExecContext(
+			ctx,
+			query,
+			args...,
+		)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n, err := result.RowsAffected()
+		return logId, n, err
+	})
+	return n, err
+}
+
+func (store *postgresStore) DeleteUserFoodLog(ctx context.Context, userId string, logId int64) (int64, error) {
+	_, n, err := store.audit(ctx, AuditDelete, userId, logId, func(tx *sql.Tx) (int64, int64, error) {
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE food_log SET deleted_at=timezone('utc', now()) WHERE user_id=$1 AND id=$2 AND deleted_at IS NULL`,
+			userId, logId,
+		)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n, err := result.RowsAffected()
+		return logId, n, err
+	})
+	return n, err
+}
+
+// FetchFoodLog returns the users food log with the ID, which has no ID when there isn't one outside the trash.
+func (store *postgresStore) FetchFoodLog(ctx context.Context, userId string, logId int64) (FoodLog, error) {
+	var foodLog FoodLog
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT id, user_id, food_item, calories, quantity, serving, date_time FROM food_log WHERE user_id=$1 AND id=$2 AND deleted_at IS NULL`,
+		userId, logId,
+	)
+
+	err := row.Scan(&foodLog.ID, &foodLog.UserID, &foodLog.FoodItem, &foodLog.Calories, &foodLog.Quantity, &foodLog.Serving, &foodLog.DateTime)
+	if err != nil && err != sql.ErrNoRows {
+		return foodLog, err
+	}
+
+	return foodLog, nil
+}
+
+func (store *postgresStore) FetchDailyFoodLogs(ctx context.Context, userId string, date time.Time) ([]FoodLog, error) {
+	dateStr := date.Format("2006-01-02")
+	rows, err := store.db.QueryContext(
+		ctx,
+		`SELECT id, user_id, food_item, calories, quantity, serving, date_time FROM food_log WHERE user_id=$1 AND date_time::date=$2::date AND deleted_at IS NULL ORDER BY date_time`,
+		userId, dateStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foodLogs []FoodLog
+	for rows.Next() {
+		var foodLog FoodLog
+
+		if err := rows.Scan(
+			&foodLog.ID, &foodLog.UserID, &foodLog.FoodItem, &foodLog.Calories, &foodLog.Quantity, &foodLog.Serving, &foodLog.DateTime,
+		); err != nil {
+			return nil, err
+		}
+		foodLogs = append(foodLogs, foodLog)
+	}
+	return foodLogs, rows.Err()
+}
+
+// CountFoodLogsAddedOn counts the food logs the user added on the date, including ones they have since deleted.
+func (store *postgresStore) CountFoodLogsAddedOn(ctx context.Context, userId string, date time.Time) (int, error) {
+	dateStr := date.Format("2006-01-02")
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM food_log_audit WHERE user_id=$1 AND action=$2 AND changed_at::date=$3::date`,
+		userId, AuditCreate, dateStr,
+	)
+
+	var count int
+	err := row.Scan(&count)
+	return count, err
+}
+
+// SearchRecentFoodLogs returns up to limit of the users food logs outside the trash, newest first, whose name contains
+// the search text or whose ID starts with it. An empty search matches every food log.
+func (store *postgresStore) SearchRecentFoodLogs(ctx context.Context, userId string, search string, limit int) ([]FoodLog, error) {
+	pattern := escapeLike(search)
+	rows, err := store.db.QueryContext(
+		ctx,
+		`SELECT id, user_id, food_item, calories, quantity, serving, date_time FROM food_log
+			WHERE user_id=$1 AND deleted_at IS NULL AND (food_item ILIKE '%' || $2 || '%' OR id::text LIKE $2 || '%')
+			ORDER BY date_time DESC, id DESC LIMIT $3`,
+		userId, pattern, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foodLogs []FoodLog
+	for rows.Next() {
+		var foodLog FoodLog
+
+		if err := rows.Scan(
+			&foodLog.ID, &foodLog.UserID, &foodLog.FoodItem, &foodLog.Calories, &foodLog.Quantity, &foodLog.Serving, &foodLog.DateTime,
+		); err != nil {
+			return nil, err
+		}
+		foodLogs = append(foodLogs, foodLog)
+	}
+	return foodLogs, rows.Err()
+}
+
+// FetchTrashedFoodLogs returns the users deleted food logs that haven't been purged yet, most recently deleted first.
+func (store *postgresStore) FetchTrashedFoodLogs(ctx context.Context, userId string) ([]FoodLog, error) {
+	rows, err := store.db.QueryContext(
+		ctx,
+		`SELECT id, user_id, food_item, calories, quantity, serving, date_time, deleted_at FROM food_log WHERE user_id=$1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foodLogs []FoodLog
+	for rows.Next() {
+		var foodLog FoodLog
+
+		if err := rows.Scan(
+			&foodLog.ID, &foodLog.UserID, &foodLog.FoodItem, &foodLog.Calories, &foodLog.Quantity, &foodLog.Serving, &foodLog.DateTime, &foodLog.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		foodLogs = append(foodLogs, foodLog)
+	}
+	return foodLogs, rows.Err()
+}
+
+func (store *postgresStore) RestoreFoodLog(ctx context.Context, userId string, logId int64) (int64, error) {
+	_, n, err := store.audit(ctx, AuditRestore, userId, logId, func(tx *sql.Tx) (int64, int64, error) {
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE food_log SET deleted_at=NULL WHERE user_id=$1 AND id=$2 AND deleted_at IS NOT NULL`,
+			userId, logId,
+		)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n, err := result.RowsAffected()
+		return logId, n, err
+	})
+	return n, err
+}
+
+// EmptyTrash permanently deletes every food log in the users trash.
+func (store *postgresStore) EmptyTrash(ctx context.Context, userId string) (int64, error) {
+	return store.deleteTrashed(ctx, `user_id=? AND deleted_at IS NOT NULL`, userId)
+}
+
+// PurgeTrash permanently deletes every users food logs that were moved to the trash before the time.
+func (store *postgresStore) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return store.deleteTrashed(ctx, `deleted_at < ?::timestamp`, formatDateTime(deletedBefore))
+}
+
+func (store *postgresStore) FetchConsumedCaloriesForDate(ctx context.Context, userId string, date time.Time) (int64, error) {
+	dateStr := date.Format("2006-01-02")
+
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT ROUND(SUM(calories*quantity))::bigint consumed FROM food_log WHERE user_id=$1 AND date_time::date=$2::date AND deleted_at IS NULL`,
+		userId, dateStr,
+	)
+
+	var consumedCalories int64
+	err := row.Scan(&consumedCalories)
+	return consumedCalories, err
+}
+
+func (store *postgresStore) FetchAverageConsumedCalories(ctx context.Context, userId string, fromDate time.Time) (int64, error) {
+	fromDateStr := fromDate.Format("2006-01-02")
+
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT ROUND(AVG(daily_sum))::bigint AS average_calories
+		FROM (
+			SELECT ROUND(SUM(calories*quantity)) daily_sum
+			FROM food_log
+			WHERE user_id=$1 AND deleted_at IS NULL
+			AND date_time::date BETWEEN $2::date AND `+postgresToday+`
+			GROUP BY date_time::date
+		) AS daily_calories`,
+		userId, fromDateStr,
+	)
+
+	var averageCalories int64
+	err := row.Scan(&averageCalories)
+	return averageCalories, err
+}
+
+func (store *postgresStore) FetchRemainingCalories(ctx context.Context, userId string, date time.Time) (int64, error) {
+	dateStr := date.Format("2006-01-02")
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT users.daily_calories - COALESCE(ROUND(SUM(food_log.calories*food_log.quantity)), 0) AS remaining_calories
+		FROM users
+		LEFT JOIN food_log ON users.id = food_log.user_id AND food_log.date_time::date=$1::date AND food_log.deleted_at IS NULL
+		WHERE users.id=$2
+		GROUP BY users.id, users.daily_calories`,
+		dateStr, userId,
+	)
+
+	var remainingCalories int64
+
+	err := row.Scan(&remainingCalories)
+
+	if err != nil && err == sql.ErrNoRows {
+		return 10000, err
+	}
+
+	return remainingCalories, err
+}
+
+func (store *postgresStore) FetchWeeksRemainingCalories(ctx context.Context, userId string, fromDate time.Time, toDate time.Time) (int64, error) {
+	fromDateStr := fromDate.Format("2006-01-02")
+	toDateStr := toDate.Format("2006-01-02")
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT SUM(totalcalsperday) AS remaining_calories
+		FROM (
+			SELECT users.id,
+			   users.daily_calories - COALESCE(ROUND(SUM(food_log.calories * food_log.quantity)), 0) AS totalcalsperday,
+			   food_log.date_time::date AS log_date
+			FROM users
+			LEFT JOIN food_log ON users.id = food_log.user_id
+			WHERE users.id = $1 AND food_log.deleted_at IS NULL
+			AND food_log.date_time::date BETWEEN $2::date AND $3::date
+			GROUP BY users.id, users.daily_calories, log_date
+		) AS daily_remaining`,
+		userId, fromDateStr, toDateStr,
+	)
+
+	var remainingCalories int64
+
+	err := row.Scan(&remainingCalories)
+
+	if err != nil && err == sql.ErrNoRows {
+		return 10000, err
+	}
+
+	return remainingCalories, err
+}
+
+func (store *postgresStore) FetchFoodLogDaysCount(ctx context.Context, userId string) (int64, error) {
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(DISTINCT date_time::date) AS days_count
+		FROM food_log
+		WHERE user_id=$1 AND deleted_at IS NULL`,
+		userId,
+	)
+
+	var daysDataCount int64
+	err := row.Scan(&daysDataCount)
+	return daysDataCount, err
+}
+
+func (store *postgresStore) FetchSavedFood(ctx context.Context, userId string, name string) (SavedFood, error) {
+	var savedFood SavedFood
+
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT user_id, name, calories, calories_per_unit FROM saved_food WHERE user_id=$1 AND name=$2`,
+		userId, strings.ToLower(name),
+	)
+
+	err := row.Scan(&savedFood.UserID, &savedFood.Name, &savedFood.Calories, &savedFood.CaloriesPerUnit)
+
+	if err != nil && err != sql.ErrNoRows {
+		return savedFood, err
+	}
+
+	return savedFood, nil
+}
+
+// SaveUserFood remembers a food for the user. Zero values keep whatever was previously saved.
+func (store *postgresStore) SaveUserFood(ctx context.Context, savedFood *SavedFood) error {
+	_, err := store.db.ExecContext(
+		ctx,
+		`INSERT INTO saved_food (user_id, name, calories, calories_per_unit) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, name) DO UPDATE SET
+			calories=CASE WHEN excluded.calories > 0 THEN excluded.calories ELSE saved_food.calories END,
+			calories_per_unit=CASE WHEN excluded.calories_per_unit > 0 THEN excluded.calories_per_unit ELSE saved_food.calories_per_unit END`,
+		savedFood.UserID, strings.ToLower(savedFood.Name), savedFood.Calories, savedFood.CaloriesPerUnit,
+	)
+	return err
+}
+
+func (store *postgresStore) CountNutrition(ctx context.Context, source string) (int64, error) {
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM nutrition WHERE source=$1`,
+		source,
+	)
+
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+// FetchNutritionByName finds the closest nutrition entry, preferring an exact name, then the builtin foods, then the best search match.
+func (store *postgresStore) FetchNutritionByName(ctx context.Context, name string) (Nutrition, error) {
+	var nutrition Nutrition
+
+	query := postgresSearchQuery(name)
+	if query == "" {
+		return nutrition, nil
+	}
+
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT id, name, kcal_100g, serving_grams, COALESCE(barcode, ''), source
+		FROM nutrition
+		WHERE to_tsvector('simple', name) @@ to_tsquery('simple', $1)
+		ORDER BY lower(name) = lower($2) DESC, source = 'builtin' DESC, ts_rank(to_tsvector('simple', name), to_tsquery('simple', $1)) DESC
+		LIMIT 1`,
+		query, name,
+	)
+
+	err := row.Scan(&nutrition.ID, &nutrition.Name, &nutrition.KcalPer100g, &nutrition.ServingGrams, &nutrition.Barcode, &nutrition.Source)
+
+	if err != nil && err != sql.ErrNoRows {
+		return nutrition, err
+	}
+
+	return nutrition, nil
+}
+
+func (store *postgresStore) FetchNutritionByBarcode(ctx context.Context, code string) (Nutrition, error) {
+	var nutrition Nutrition
+
+	barcode := NormaliseBarcode(code)
+	if barcode == "" {
+		return nutrition, nil
+	}
+
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT id, name, kcal_100g, serving_grams, barcode, source FROM nutrition WHERE barcode=$1 LIMIT 1`,
+		barcode,
+	)
+
+	err := row.Scan(&nutrition.ID, &nutrition.Name, &nutrition.KcalPer100g, &nutrition.ServingGrams, &nutrition.Barcode, &nutrition.Source)
+
+	if err != nil && err != sql.ErrNoRows {
+		return nutrition, err
+	}
+
+	return nutrition, nil
+}
+
+func (store *postgresStore) SearchNutrition(ctx context.Context, text string, limit int) ([]Nutrition, error) {
+	query := postgresSearchQuery(text)
+	if query == "" {
+		return nil, nil
+	}
+
+	rows, err := store.db.QueryContext(
+		ctx,
+		`SELECT id, name, kcal_100g, serving_grams, COALESCE(barcode, ''), source
+		FROM nutrition
+		WHERE to_tsvector('simple', name) @@ to_tsquery('simple', $1)
+		ORDER BY ts_rank(to_tsvector('simple', name), to_tsquery('simple', $1)) DESC, id
+		LIMIT $2`,
+		query, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []Nutrition
+	for rows.Next() {
+		var nutrition Nutrition
+
+		if err := rows.Scan(
+			&nutrition.ID, &nutrition.Name, &nutrition.KcalPer100g, &nutrition.ServingGrams, &nutrition.Barcode, &nutrition.Source,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, nutrition)
+	}
+	return results, rows.Err()
+}
+
+// ImportNutrition replaces every entry from the source with the entries passed to add by read, copying them in a single transaction.
+func (store *postgresStore) ImportNutrition(ctx context.Context, source string, read func(add func(Nutrition) error) error) (int64, error) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`DELETE FROM nutrition WHERE source=$1`,
+		source,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		pq.CopyIn("nutrition", "name", "kcal_100g", "serving_grams", "barcode", "source"),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var count int64
+	err = read(func(nutrition Nutrition) error {
+		var barcode any
+		if nutrition.Barcode != "" {
+			barcode = nutrition.Barcode
+		}
+
+		_, err := stmt.ExecContext(
+			ctx,
+			nutrition.Name, nutrition.KcalPer100g, nutrition.ServingGrams, barcode, source,
+		)
+		if err != nil {
+			return err
+		}
+
+		count++
+		if count%50000 == 0 {
+			logging.FromContext(ctx).Info("Imported nutrition entries so far", "count", count, "source", source)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Flush the buffered rows
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
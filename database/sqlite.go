@@ -0,0 +1,717 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/discordcalorietracker/logging"
+)
+
+type sqliteStore struct {
+	*migrator
+	*auditor
+	db *sql.DB
+}
+
+func openSQLite(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// Every connection to :memory: gets its own empty database, so they all have to share one
+	if path == ":memory:" {
+		db.SetMaxOpenConns(1)
+	}
+
+	store := &sqliteStore{db: db}
+	store.migrator = &migrator{
+		db:  db,
+		dir: "sqlite",
+		bind: func(query string) string {
+			return query
+		},
+		baseline: store.baselineLegacySchema,
+	}
+	store.auditor = &auditor{db: db, bind: store.migrator.bind}
+	return store, nil
+}
+
+func (store *sqliteStore) Close() error {
+	return store.db.Close()
+}
+
+func (store *sqliteStore) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
+
+// baselineLegacySchema works out how far databases created before versioned migrations existed got from the tables and columns present.
+// Only migrations from before then are checked, later ones always run through the migrator.
+func (store *sqliteStore) baselineLegacySchema(ctx context.Context, migrations []Migration) (int, error) {
+	checks := []struct {
+		version int
+		query   string
+	}{
+		{4, `SELECT COUNT(*) FROM pragma_table_info('user') WHERE name='energy_unit'`},
+		{3, `SELECT COUNT(*) FROM pragma_table_info('nutrition') WHERE name='barcode'`},
+		{2, `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='nutrition'`},
+		{1, `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='user'`},
+	}
+
+	for _, check := range checks {
+		var count int
+		row := store.db.QueryRowContext(ctx, check.query)
+		if err := row.Scan(&count); err != nil {
+			return 0, err
+		}
+		if count > 0 {
+			return check.version, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func (store *sqliteStore) FetchUserByID(ctx context.Context, id string) (User, error) {
+	var user User
+
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT id, daily_calories, day_streak, last_logged, energy_unit FROM user WHERE id=?`, id,
+	)
+
+	err := row.Scan(&user.ID, &user.DailyCalories, &user.DayStreak, &user.LastLogged, &user.EnergyUnit)
+
+	if err != nil && err != sql.ErrNoRows {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// RegisterUser creates the user without daily calories if they don't exist yet, then returns them.
+func (store *sqliteStore) RegisterUser(ctx context.Context, id string) (User, error) {
+	_, err := store.db.ExecContext(
+		ctx,
+		`INSERT INTO user (id, daily_calories) VALUES (?, 0) ON CONFLICT (id) DO NOTHING`, id,
+	)
+	if err != nil {
+		return User{}, err
+	}
+
+	return store.FetchUserByID(ctx, id)
+}
+
+func (store *sqliteStore) SetUserCalories(ctx context.Context, user *User) error {
+	logging.FromContext(ctx).Debug("Setting the calories in the database")
+	_, err := store.db.ExecContext(
+		ctx,
+		`INSERT INTO user (id, daily_calories) VALUES (?,?) ON CONFLICT (id) DO UPDATE SET daily_calories=excluded.daily_calories`,
+		user.ID, user.DailyCalories,
+	)
+	return err
+}
+
+func (store *sqliteStore) SetUserEnergyUnit(ctx context.Context, userId string, energyUnit string) (int64, error) {
+	result, err := store.db.ExecContext(
+		ctx,
+		`UPDATE user SET energy_unit=? WHERE id=?`,
+		energyUnit, userId,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (store *sqliteStore) UpdateUserStreak(ctx context.Context, userId string) (int64, error) {
+	result, err := store.db.ExecContext(
+		ctx,
+		`UPDATE user SET day_streak = CASE WHEN last_logged = date('now', '-1 day') THEN day_streak + 1 ELSE 1 END, last_logged = CURRENT_DATE WHERE id = ?;`,
+		userId,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (store *sqliteStore) AddUserFoodLog(ctx context.Context, foodLog *FoodLog) (int64, error) {
+	logging.FromContext(ctx).Debug("Adding a food log to the database")
+	id, _, err := store.audit(ctx, AuditCreate, foodLog.UserID, 0, func(tx *sql.Tx) (int64, int64, error) {
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO food_log (user_id, food_item, calories, quantity, serving) VALUES (?, ?, ?, ?, ?)`,
+			foodLog.UserID, foodLog.FoodItem, foodLog.Calories, foodLog.Quantity, foodLog.Serving,
+		)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		id, err := result.LastInsertId()
+		return id, 1, err
+	})
+
+	return id, err
+}
+
+func (store *sqliteStore) UpdateUserFoodLog(ctx context.Context, foodLog *FoodLog) (int64, error) {
+	_, n, err := store.audit(ctx, AuditUpdate, foodLog.UserID, foodLog.ID, func(tx *sql.Tx) (int64, int64, error) {
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE food_log SET food_item=?, calories=?, quantity=?, serving=? WHERE id=? AND user_id=? AND deleted_at IS NULL`,
+			foodLog.FoodItem, foodLog.Calories, foodLog.Quantity, foodLog.Serving, foodLog.ID, foodLog.UserID,
+		)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n, err := result.RowsAffected()
+		return foodLog.ID, n, err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// UpdateFoodLogQuantity adds or takes the step from the quantity, which can't go down to 0 or below.
+func (store *sqliteStore) UpdateFoodLogQuantity(ctx context.Context, userId string, logId int64, direction string, step float64) (int64, error) {
+	var query string
+	args := []any{step, logId, userId}
+	switch direction {
+	case "inc":
+		query = `UPDATE food_log SET quantity=ROUND(quantity+?, 2), date_time=CURRENT_TIMESTAMP WHERE id=? AND user_id=? AND deleted_at IS NULL`
+	case "dec":
+		query = `UPDATE food_log SET quantity=ROUND(quantity-?, 2), date_time=CURRENT_TIMESTAMP WHERE id=? AND user_id=? AND deleted_at IS NULL AND quantity > ?`
+		args = append(args, step)
+	default:
+		return 0, errors.New("invalid direction")
+	}
+
+	_, n, err := store.audit(ctx, AuditQuantity, userId, logId, func(tx *sql.Tx) (int64, int64, error) {
+		result, err := tx.ExecContext(
+			ctx,
+			query,
+			args...,
+		)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n, err := result.RowsAffected()
+		return logId, n, err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (store *sqliteStore) DeleteUserFoodLog(ctx context.Context, userId string, logId int64) (int64, error) {
+	_, n, err := store.audit(ctx, AuditDelete, userId, logId, func(tx *sql.Tx) (int64, int64, error) {
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE food_log SET deleted_at=CURRENT_TIMESTAMP WHERE user_id=? AND id=? AND deleted_at IS NULL`,
+			userId, logId,
+		)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n, err := result.RowsAffected()
+		return logId, n, err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// FetchFoodLog returns the users food log with the ID, which has no ID when there isn't one outside the trash.
+func (store *sqliteStore) FetchFoodLog(ctx context.Context, userId string, logId int64) (FoodLog, error) {
+	var foodLog FoodLog
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT id, user_id, food_item, calories, quantity, serving, date_time FROM food_log WHERE user_id=? AND id=? AND deleted_at IS NULL`,
+		userId, logId,
+	)
+
+	err := row.Scan(&foodLog.ID, &foodLog.UserID, &foodLog.FoodItem, &foodLog.Calories, &foodLog.Quantity, &foodLog.Serving, &foodLog.DateTime)
+	if err != nil && err != sql.ErrNoRows {
+		return foodLog, err
+	}
+
+	return foodLog, nil
+}
+
+func (store *sqliteStore) FetchDailyFoodLogs(ctx context.Context, userId string, date time.Time) ([]FoodLog, error) {
+	dateStr := date.Format("2006-01-02")
+	var foodLogs []FoodLog
+	rows, err := store.db.QueryContext(
+		ctx,
+		`SELECT id, user_id, food_item, calories, quantity, serving, date_time FROM food_log WHERE user_id=? AND DATE(date_time)=? AND deleted_at IS NULL ORDER BY date_time`,
+		userId, dateStr,
+	)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var foodLog FoodLog
+
+		if err := rows.Scan(
+			&foodLog.ID, &foodLog.UserID, &foodLog.FoodItem, &foodLog.Calories, &foodLog.Quantity, &foodLog.Serving, &foodLog.DateTime,
+		); err != nil {
+			return nil, err
+		}
+		foodLogs = append(foodLogs, foodLog)
+	}
+	return foodLogs, err
+}
+
+// CountFoodLogsAddedOn counts the food logs the user added on the date, including ones they have since deleted.
+func (store *sqliteStore) CountFoodLogsAddedOn(ctx context.Context, userId string, date time.Time) (int, error) {
+	dateStr := date.Format("2006-01-02")
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM food_log_audit WHERE user_id=? AND action=? AND DATE(changed_at)=?`,
+		userId, AuditCreate, dateStr,
+	)
+
+	var count int
+	err := row.Scan(&count)
+	return count, err
+}
+
+// SearchRecentFoodLogs returns up to limit of the users food logs outside the trash, newest first, whose name contains
+// the search text or whose ID starts with it. An empty search matches every food log.
+func (store *sqliteStore) SearchRecentFoodLogs(ctx context.Context, userId string, search string, limit int) ([]FoodLog, error) {
+	pattern := escapeLike(search)
+	rows, err := store.db.QueryContext(
+		ctx,
+		`SELECT id, user_id, food_item, calories, quantity, serving, date_time FROM food_log
+			WHERE user_id=? AND deleted_at IS NULL AND (food_item LIKE '%' || ? || '%' ESCAPE '\' OR CAST(id AS TEXT) LIKE ? || '%' ESCAPE '\')
+			ORDER BY date_time DESC, id DESC LIMIT ?`,
+		userId, pattern, pattern, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foodLogs []FoodLog
+	for rows.Next() {
+		var foodLog FoodLog
+
+		if err := rows.Scan(
+			&foodLog.ID, &foodLog.UserID, &foodLog.FoodItem, &foodLog.Calories, &foodLog.Quantity, &foodLog.Serving, &foodLog.DateTime,
+		); err != nil {
+			return nil, err
+		}
+		foodLogs = append(foodLogs, foodLog)
+	}
+	return foodLogs, rows.Err()
+}
+
+// FetchTrashedFoodLogs returns the users deleted food logs that haven't been purged yet, most recently deleted first.
+func (store *sqliteStore) FetchTrashedFoodLogs(ctx context.Context, userId string) ([]FoodLog, error) {
+	rows, err := store.db.QueryContext(
+		ctx,
+		`SELECT id, user_id, food_item, calories, quantity, serving, date_time, deleted_at FROM food_log WHERE user_id=? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foodLogs []FoodLog
+	for rows.Next() {
+		var foodLog FoodLog
+
+		if err := rows.Scan(
+			&foodLog.ID, &foodLog.UserID, &foodLog.FoodItem, &foodLog.Calories, &foodLog.Quantity, &foodLog.Serving, &foodLog.DateTime, &foodLog.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		foodLogs = append(foodLogs, foodLog)
+	}
+	return foodLogs, rows.Err()
+}
+
+func (store *sqliteStore) RestoreFoodLog(ctx context.Context, userId string, logId int64) (int64, error) {
+	_, n, err := store.audit(ctx, AuditRestore, userId, logId, func(tx *sql.Tx) (int64, int64, error) {
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE food_log SET deleted_at=NULL WHERE user_id=? AND id=? AND deleted_at IS NOT NULL`,
+			userId, logId,
+		)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		n, err := result.RowsAffected()
+		return logId, n, err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// EmptyTrash permanently deletes every food log in the users trash.
+func (store *sqliteStore) EmptyTrash(ctx context.Context, userId string) (int64, error) {
+	return store.deleteTrashed(ctx, `user_id=? AND deleted_at IS NOT NULL`, userId)
+}
+
+// PurgeTrash permanently deletes every users food logs that were moved to the trash before the time.
+func (store *sqliteStore) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return store.deleteTrashed(ctx, `deleted_at < ?`, formatDateTime(deletedBefore))
+}
+
+func (store *sqliteStore) FetchConsumedCaloriesForDate(ctx context.Context, userId string, date time.Time) (int64, error) {
+	dateStr := date.Format("2006-01-02")
+
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT ROUND(SUM(calories*quantity)) consumed FROM food_log WHERE user_id=? AND DATE(date_time)=? AND deleted_at IS NULL`,
+		userId, dateStr,
+	)
+
+	var consumedCalories int64
+
+	err := row.Scan(&consumedCalories)
+
+	if err != nil && err == sql.ErrNoRows {
+		return 0, err
+	}
+
+	if err != nil {
+		return consumedCalories, err
+	}
+
+	return consumedCalories, nil
+}
+
+func (store *sqliteStore) FetchAverageConsumedCalories(ctx context.Context, userId string, fromDate time.Time) (int64, error) {
+	fromDateStr := fromDate.Format("2006-01-02")
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT ROUND(AVG(daily_sum), 0) AS average_calories
+		FROM (
+			SELECT ROUND(SUM(calories*quantity)) daily_sum
+			FROM food_log 
+			WHERE user_id=? AND deleted_at IS NULL
+			AND DATE(date_time) BETWEEN ? AND CURRENT_DATE
+			GROUP BY DATE(date_time)
+		) AS daily_calories`,
+		userId, fromDateStr,
+	)
+
+	var averageCalories int64
+
+	err := row.Scan(&averageCalories)
+	if err != nil && err == sql.ErrNoRows {
+		return 0, err
+	}
+
+	if err != nil {
+		return averageCalories, err
+	}
+
+	return averageCalories, nil
+}
+
+func (store *sqliteStore) FetchRemainingCalories(ctx context.Context, userId string, date time.Time) (int64, error) {
+	dateStr := date.Format("2006-01-02")
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT user.daily_calories - COALESCE(ROUND(SUM(food_log.calories*food_log.quantity)), 0) AS remaining_calories
+		FROM user
+		LEFT JOIN food_log ON user.id = food_log.user_id AND DATE(food_log.date_time)=? AND food_log.deleted_at IS NULL
+		WHERE user.id=?
+		GROUP BY user.id, user.daily_calories;`,
+		dateStr, userId,
+	)
+
+	var remainingCalories int64
+
+	err := row.Scan(&remainingCalories)
+
+	if err != nil && err == sql.ErrNoRows {
+		return 10000, err
+	}
+
+	if err != nil {
+		return remainingCalories, err
+	}
+
+	return remainingCalories, nil
+}
+
+func (store *sqliteStore) FetchWeeksRemainingCalories(ctx context.Context, userId string, fromDate time.Time, toDate time.Time) (int64, error) {
+	fromDateStr := fromDate.Format("2006-01-02")
+	toDateStr := toDate.Format("2006-01-02")
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT SUM(totalcalsperday) as remaining_calories
+		FROM (
+			SELECT user.id,
+			   user.daily_calories - COALESCE(ROUND(SUM(food_log.calories * food_log.quantity)), 0) AS totalcalsperday,
+			   DATE(food_log.date_time) AS log_date
+			FROM user
+			LEFT JOIN food_log ON user.id = food_log.user_id
+			WHERE user.id = ? AND food_log.deleted_at IS NULL
+			AND DATE(date_time) BETWEEN ? AND ?
+			GROUP BY user.id, user.daily_calories, log_date
+		);`,
+		userId, fromDateStr, toDateStr,
+	)
+
+	var remainingCalories int64
+
+	err := row.Scan(&remainingCalories)
+
+	if err != nil && err == sql.ErrNoRows {
+		return 10000, err
+	}
+
+	if err != nil {
+		return remainingCalories, err
+	}
+
+	return remainingCalories, nil
+}
+
+func (store *sqliteStore) FetchFoodLogDaysCount(ctx context.Context, userId string) (int64, error) {
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(DISTINCT DATE(date_time)) AS days_count
+		FROM food_log
+		WHERE user_id=? AND deleted_at IS NULL`,
+		userId,
+	)
+
+	var daysDataCount int64
+
+	err := row.Scan(&daysDataCount)
+	if err != nil && err == sql.ErrNoRows {
+		return 0, err
+	}
+
+	if err != nil {
+		return daysDataCount, err
+	}
+
+	return daysDataCount, nil
+}
+
+// searchQuery turns user input into an FTS5 query matching every word as a prefix.
+func searchQuery(text string) string {
+	var terms []string
+	for _, word := range searchTerms(text) {
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+func (store *sqliteStore) FetchSavedFood(ctx context.Context, userId string, name string) (SavedFood, error) {
+	var savedFood SavedFood
+
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT user_id, name, calories, calories_per_unit FROM saved_food WHERE user_id=? AND name=?`,
+		userId, strings.ToLower(name),
+	)
+
+	err := row.Scan(&savedFood.UserID, &savedFood.Name, &savedFood.Calories, &savedFood.CaloriesPerUnit)
+
+	if err != nil && err != sql.ErrNoRows {
+		return savedFood, err
+	}
+
+	return savedFood, nil
+}
+
+// SaveUserFood remembers a food for the user. Zero values keep whatever was previously saved.
+func (store *sqliteStore) SaveUserFood(ctx context.Context, savedFood *SavedFood) error {
+	_, err := store.db.ExecContext(
+		ctx,
+		`INSERT INTO saved_food (user_id, name, calories, calories_per_unit) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, name) DO UPDATE SET
+			calories=CASE WHEN excluded.calories > 0 THEN excluded.calories ELSE saved_food.calories END,
+			calories_per_unit=CASE WHEN excluded.calories_per_unit > 0 THEN excluded.calories_per_unit ELSE saved_food.calories_per_unit END`,
+		savedFood.UserID, strings.ToLower(savedFood.Name), savedFood.Calories, savedFood.CaloriesPerUnit,
+	)
+	return err
+}
+
+func (store *sqliteStore) CountNutrition(ctx context.Context, source string) (int64, error) {
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM nutrition WHERE source=?`,
+		source,
+	)
+
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+// FetchNutritionByName finds the closest nutrition entry, preferring an exact name, then the builtin foods, then the best search match.
+func (store *sqliteStore) FetchNutritionByName(ctx context.Context, name string) (Nutrition, error) {
+	var nutrition Nutrition
+
+	query := searchQuery(name)
+	if query == "" {
+		return nutrition, nil
+	}
+
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT nutrition.id, nutrition.name, nutrition.kcal_100g, nutrition.serving_grams, COALESCE(nutrition.barcode, ''), nutrition.source
+		FROM nutrition_fts
+		JOIN nutrition ON nutrition.id = nutrition_fts.rowid
+		WHERE nutrition_fts MATCH ?
+		ORDER BY nutrition.name = ? COLLATE NOCASE DESC, nutrition.source = 'builtin' DESC, nutrition_fts.rank
+		LIMIT 1`,
+		query, name,
+	)
+
+	err := row.Scan(&nutrition.ID, &nutrition.Name, &nutrition.KcalPer100g, &nutrition.ServingGrams, &nutrition.Barcode, &nutrition.Source)
+
+	if err != nil && err != sql.ErrNoRows {
+		return nutrition, err
+	}
+
+	return nutrition, nil
+}
+
+func (store *sqliteStore) FetchNutritionByBarcode(ctx context.Context, code string) (Nutrition, error) {
+	var nutrition Nutrition
+
+	barcode := NormaliseBarcode(code)
+	if barcode == "" {
+		return nutrition, nil
+	}
+
+	row := store.db.QueryRowContext(
+		ctx,
+		`SELECT id, name, kcal_100g, serving_grams, barcode, source FROM nutrition WHERE barcode=? LIMIT 1`,
+		barcode,
+	)
+
+	err := row.Scan(&nutrition.ID, &nutrition.Name, &nutrition.KcalPer100g, &nutrition.ServingGrams, &nutrition.Barcode, &nutrition.Source)
+
+	if err != nil && err != sql.ErrNoRows {
+		return nutrition, err
+	}
+
+	return nutrition, nil
+}
+
+func (store *sqliteStore) SearchNutrition(ctx context.Context, text string, limit int) ([]Nutrition, error) {
+	query := searchQuery(text)
+	if query == "" {
+		return nil, nil
+	}
+
+	rows, err := store.db.QueryContext(
+		ctx,
+		`SELECT nutrition.id, nutrition.name, nutrition.kcal_100g, nutrition.serving_grams, COALESCE(nutrition.barcode, ''), nutrition.source
+		FROM nutrition_fts
+		JOIN nutrition ON nutrition.id = nutrition_fts.rowid
+		WHERE nutrition_fts MATCH ?
+		ORDER BY nutrition_fts.rank
+		LIMIT ?`,
+		query, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []Nutrition
+	for rows.Next() {
+		var nutrition Nutrition
+
+		if err := rows.Scan(
+			&nutrition.ID, &nutrition.Name, &nutrition.KcalPer100g, &nutrition.ServingGrams, &nutrition.Barcode, &nutrition.Source,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, nutrition)
+	}
+	return results, rows.Err()
+}
+
+// ImportNutrition replaces every entry from the source with the entries passed to add by read, in a single transaction.
+func (store *sqliteStore) ImportNutrition(ctx context.Context, source string, read func(add func(Nutrition) error) error) (int64, error) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`DELETE FROM nutrition WHERE source=?`,
+		source,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		`INSERT INTO nutrition (name, kcal_100g, serving_grams, barcode, source) VALUES (?, ?, ?, NULLIF(?, ''), ?)`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var count int64
+	err = read(func(nutrition Nutrition) error {
+		_, err := stmt.ExecContext(
+			ctx,
+			nutrition.Name, nutrition.KcalPer100g, nutrition.ServingGrams, nutrition.Barcode, source,
+		)
+		if err != nil {
+			return err
+		}
+
+		count++
+		if count%50000 == 0 {
+			logging.FromContext(ctx).Info("Imported nutrition entries so far", "count", count, "source", source)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
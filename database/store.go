@@ -0,0 +1,254 @@
+package database
+
+import (
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/discordcalorietracker/logging"
+)
+
+type User struct {
+	ID            string
+	DailyCalories int16
+	DayStreak     int16
+	LastLogged    time.Time
+	EnergyUnit    string
+}
+
+// HasDailyCalories reports whether the user has set their daily calories with /set.
+func (user User) HasDailyCalories() bool {
+	return user.DailyCalories > 0
+}
+
+type FoodLog struct {
+	ID       int64
+	UserID   string
+	FoodItem string
+	// Calories are for a single serving, eating Quantity servings
+	Calories int16
+	Quantity float64
+	// Serving labels what one serving is, e.g slice or 100 g, it is empty when the food is counted in items
+	Serving  string
+	DateTime time.Time
+	// DeletedAt is only set for logs in the trash
+	DeletedAt time.Time
+}
+
+type SavedFood struct {
+	UserID          string
+	Name            string
+	Calories        int16
+	CaloriesPerUnit float64
+}
+
+type Nutrition struct {
+	ID           int64
+	Name         string
+	KcalPer100g  float64
+	ServingGrams float64
+	Barcode      string
+	Source       string
+}
+
+// Store is all of the data access used by the bot, implemented for SQLite and PostgreSQL.
+type Store interface {
+	FetchUserByID(ctx context.Context, id string) (User, error)
+	RegisterUser(ctx context.Context, id string) (User, error)
+	SetUserCalories(ctx context.Context, user *User) error
+	SetUserEnergyUnit(ctx context.Context, userId string, energyUnit string) (int64, error)
+	UpdateUserStreak(ctx context.Context, userId string) (int64, error)
+
+	AddUserFoodLog(ctx context.Context, foodLog *FoodLog) (int64, error)
+	UpdateUserFoodLog(ctx context.Context, foodLog *FoodLog) (int64, error)
+	UpdateFoodLogQuantity(ctx context.Context, userId string, logId int64, direction string, step float64) (int64, error)
+	DeleteUserFoodLog(ctx context.Context, userId string, logId int64) (int64, error)
+	FetchFoodLog(ctx context.Context, userId string, logId int64) (FoodLog, error)
+	FetchDailyFoodLogs(ctx context.Context, userId string, date time.Time) ([]FoodLog, error)
+	CountFoodLogsAddedOn(ctx context.Context, userId string, date time.Time) (int, error)
+	SearchRecentFoodLogs(ctx context.Context, userId string, search string, limit int) ([]FoodLog, error)
+	FetchFoodLogHistory(ctx context.Context, userId string, logId int64) ([]FoodLogAudit, error)
+	UndoFoodLogChange(ctx context.Context, userId string, logId int64) (FoodLogAudit, error)
+	FetchTrashedFoodLogs(ctx context.Context, userId string) ([]FoodLog, error)
+	RestoreFoodLog(ctx context.Context, userId string, logId int64) (int64, error)
+	EmptyTrash(ctx context.Context, userId string) (int64, error)
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error)
+
+	FetchConsumedCaloriesForDate(ctx context.Context, userId string, date time.Time) (int64, error)
+	FetchAverageConsumedCalories(ctx context.Context, userId string, fromDate time.Time) (int64, error)
+	FetchRemainingCalories(ctx context.Context, userId string, date time.Time) (int64, error)
+	FetchWeeksRemainingCalories(ctx context.Context, userId string, fromDate time.Time, toDate time.Time) (int64, error)
+	FetchFoodLogDaysCount(ctx context.Context, userId string) (int64, error)
+
+	FetchSavedFood(ctx context.Context, userId string, name string) (SavedFood, error)
+	SaveUserFood(ctx context.Context, savedFood *SavedFood) error
+
+	CountNutrition(ctx context.Context, source string) (int64, error)
+	FetchNutritionByName(ctx context.Context, name string) (Nutrition, error)
+	FetchNutritionByBarcode(ctx context.Context, code string) (Nutrition, error)
+	SearchNutrition(ctx context.Context, text string, limit int) ([]Nutrition, error)
+	ImportNutrition(ctx context.Context, source string, read func(add func(Nutrition) error) error) (int64, error)
+
+	Migrations() ([]Migration, error)
+	SchemaVersion(ctx context.Context) (int, error)
+	MigrateUp(ctx context.Context, target int) error
+	MigrateDown(ctx context.Context, steps int) error
+
+	// Ping checks the database can still be reached
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+var DB Store
+
+// Open connects to the database described by the DSN without touching the schema.
+// postgres:// and postgresql:// URLs use PostgreSQL, anything else is a SQLite file path.
+func Open(dsn string) (Store, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return openPostgres(dsn)
+	}
+	return openSQLite(strings.TrimPrefix(dsn, "sqlite://"))
+}
+
+// OpenDatabase connects to the database without touching the schema.
+func OpenDatabase(dsn string) {
+	var err error
+	DB, err = Open(dsn)
+	if err != nil {
+		log.Fatalf("Could not connect to DB: %v", err)
+	}
+}
+
+// InitDatabase connects to the database and upgrades it to the latest schema.
+func InitDatabase(ctx context.Context, dsn string) {
+	OpenDatabase(dsn)
+
+	if err := Migrate(ctx, DB); err != nil {
+		log.Fatalf("Could not prepare the DB: %v", err)
+	}
+	logging.FromContext(ctx).Info("Connected to the DB")
+}
+
+// Migrate upgrades the store to the latest schema and seeds the builtin nutrition data.
+func Migrate(ctx context.Context, store Store) error {
+	if err := store.MigrateUp(ctx, 0); err != nil {
+		return fmt.Errorf("migrating schema: %w", err)
+	}
+	if err := seedNutrition(ctx, store); err != nil {
+		return fmt.Errorf("seeding nutrition data: %w", err)
+	}
+	return nil
+}
+
+// TrashRetention is how long deleted food logs can be restored before PurgeTrashEvery removes them for good.
+const TrashRetention = 30 * 24 * time.Hour
+
+// PurgeTrashEvery purges food logs that have been in the trash for longer than TrashRetention straight away and then
+// every interval, until the context is cancelled.
+func PurgeTrashEvery(ctx context.Context, store Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := store.PurgeTrash(ctx, time.Now().Add(-TrashRetention))
+		if err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).Error("Could not purge the trash", "error", err)
+		} else if n > 0 {
+			logging.FromContext(ctx).Info("Purged food logs from the trash", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//go:embed data/nutrition.csv
+var builtinNutrition string
+
+// seedNutrition loads the common foods bundled with the binary so lookups work without any imports.
+func seedNutrition(ctx context.Context, store Store) error {
+	count, err := store.CountNutrition(ctx, "builtin")
+	if err != nil || count > 0 {
+		return err
+	}
+
+	records, err := csv.NewReader(strings.NewReader(builtinNutrition)).ReadAll()
+	if err != nil {
+		return err
+	}
+
+	count, err = store.ImportNutrition(ctx, "builtin", func(add func(Nutrition) error) error {
+		// Skip the header row
+		for _, record := range records[1:] {
+			kcal, kcalErr := strconv.ParseFloat(record[1], 64)
+			serving, servingErr := strconv.ParseFloat(record[2], 64)
+			if kcalErr != nil || servingErr != nil {
+				logging.FromContext(ctx).Warn("Skipping invalid builtin nutrition row", "row", record)
+				continue
+			}
+
+			if err := add(Nutrition{Name: record[0], KcalPer100g: kcal, ServingGrams: serving}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("Seeded builtin nutrition entries", "count", count)
+	return nil
+}
+
+// searchTerms splits user input into the words to search for, dropping anything that has a meaning in search syntax.
+func searchTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		word = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, word)
+		if word != "" {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// escapeLike escapes the LIKE wildcards in user input with a backslash, so they match literally.
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
+
+// NormaliseBarcode strips anything that isn't a digit and converts the code to the 13 digit EAN form,
+// so UPC-A, EAN-13 and zero padded GTIN-14 versions of the same code all match.
+func NormaliseBarcode(code string) string {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, code)
+
+	if len(digits) < 8 {
+		return ""
+	}
+
+	digits = strings.TrimLeft(digits, "0")
+	if len(digits) < 13 {
+		digits = strings.Repeat("0", 13-len(digits)) + digits
+	}
+	return digits
+}
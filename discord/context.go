@@ -0,0 +1,116 @@
+package discord
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/logging"
+)
+
+// Context is passed to every handler, it is done when the handler should give up on its work.
+type Context struct {
+	context.Context
+	Session     Responder
+	Interaction *discordgo.InteractionCreate
+	Store       database.Store
+
+	// User triggered the interaction, Account is their stored account once RegisterUser has run
+	User    *discordgo.User
+	Account database.User
+
+	// Options holds a commands top level options by name, it is empty for components and modals
+	Options map[string]*discordgo.ApplicationCommandInteractionDataOption
+
+	// Kind is command, autocomplete, component or modal, Name is the command name or custom ID prefix
+	Kind string
+	Name string
+
+	panicked bool
+}
+
+// NewContext reads who triggered the interaction and its options, ready to be passed to a handler.
+func NewContext(ctx context.Context, s Responder, i *discordgo.InteractionCreate, store database.Store) *Context {
+	c := &Context{
+		Context:     ctx,
+		Session:     s,
+		Interaction: i,
+		Store:       store,
+		User:        InvokingUser(i),
+		Options:     map[string]*discordgo.ApplicationCommandInteractionDataOption{},
+	}
+
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+		data := i.ApplicationCommandData()
+		c.Kind, c.Name = "command", data.Name
+		if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+			c.Kind = "autocomplete"
+		}
+		for _, option := range data.Options {
+			c.Options[option.Name] = option
+		}
+
+	case discordgo.InteractionMessageComponent:
+		c.Kind, c.Name = "component", strings.Split(c.CustomID(), "_")[0]
+
+	case discordgo.InteractionModalSubmit:
+		c.Kind, c.Name = "modal", strings.Split(c.CustomID(), "_")[0]
+	}
+	return c
+}
+
+// CustomID returns the custom ID of the component or modal the interaction came from, it is empty for commands.
+func (c *Context) CustomID() string {
+	switch c.Interaction.Type {
+	case discordgo.InteractionMessageComponent:
+		return c.Interaction.MessageComponentData().CustomID
+	case discordgo.InteractionModalSubmit:
+		return c.Interaction.ModalSubmitData().CustomID
+	}
+	return ""
+}
+
+// ModalValue returns what the user entered in the modal text input with the custom ID.
+func (c *Context) ModalValue(customID string) string {
+	if c.Interaction.Type != discordgo.InteractionModalSubmit {
+		return ""
+	}
+
+	for _, component := range c.Interaction.ModalSubmitData().Components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, component := range row.Components {
+			if input, ok := component.(*discordgo.TextInput); ok && input.CustomID == customID {
+				return input.Value
+			}
+		}
+	}
+	return ""
+}
+
+// Respond sends the response to the interaction.
+func (c *Context) Respond(resp *discordgo.InteractionResponse) error {
+	return c.Session.InteractionRespond(c.Interaction.Interaction, resp)
+}
+
+// UserOption returns the user chosen in a user option, using the resolved data Discord sends with the interaction when it can.
+func (c *Context) UserOption(option *discordgo.ApplicationCommandInteractionDataOption) *discordgo.User {
+	userId := option.Value.(string)
+
+	if resolved := c.Interaction.ApplicationCommandData().Resolved; resolved != nil {
+		if user, ok := resolved.Users[userId]; ok {
+			return user
+		}
+	}
+
+	user, err := c.Session.User(userId)
+	if err != nil {
+		logging.FromContext(c).Warn("Could not fetch user", "option_user_id", userId, "error", err)
+		return &discordgo.User{ID: userId}
+	}
+	return user
+}
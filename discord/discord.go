@@ -1,17 +1,61 @@
 package discord
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/monitoring"
+	"github.com/discordcalorietracker/ratelimit"
+)
+
+// Responder is the part of the Discord session used by handlers, so they can be run against a fake in tests.
+type Responder interface {
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	User(userID string, options ...discordgo.RequestOption) (*discordgo.User, error)
+}
+
+// Handler handles a command, autocomplete, component or modal interaction, giving up on its work once the context is done.
+type Handler func(c *Context)
+
+// Discord closes the interaction if there is no response within 3 seconds, handlers still running by deferAfter get a deferred
+// response instead, which can be followed up for 15 minutes
+const (
+	responseWindow  = 3 * time.Second
+	deferAfter      = responseWindow - time.Second
+	handlerTimeout  = 30 * time.Second
+	minimumHandling = time.Second
 )
 
 var S *discordgo.Session
+var store database.Store
+var rootCtx = context.Background()
+var inFlight sync.WaitGroup
+var adminChannelID string
+var redactUsers bool
+var limiter *ratelimit.Limiter
+var maxDailyEntries int
+
+// gatewayConnected tracks the websocket, servingHTTP is set when interactions come over HTTP instead
+var gatewayConnected atomic.Bool
+var servingHTTP atomic.Bool
 var commandDefinitions []*discordgo.ApplicationCommand
-var registeredCommands []*discordgo.ApplicationCommand
-var commandHandlers map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate)
-var componentHandlers map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate)
+var commandHandlers map[string]Handler
+var componentHandlers map[string]Handler
+var modalComponents map[string]bool
+var autocompleteHandlers map[string]Handler
 
 func InitDiscordSession(botToken string) {
 	var err error
@@ -20,19 +64,70 @@ func InitDiscordSession(botToken string) {
 		log.Fatalf("Invalid bot parameters: %v", err)
 	}
 	S.AddHandler(onReady)
+	S.AddHandler(onResumed)
+	S.AddHandler(onDisconnect)
 	S.AddHandler(handleCommands)
 }
 
-func InitDiscordCommands(cmdDefinitions []*discordgo.ApplicationCommand, cmdHandlers map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate)) {
+// InitDiscordStore sets the store passed to every handler.
+func InitDiscordStore(handlerStore database.Store) {
+	store = handlerStore
+}
+
+// InitDiscordContext sets the context every interaction context is derived from, cancelling it cancels in flight work.
+func InitDiscordContext(ctx context.Context) {
+	rootCtx = ctx
+}
+
+// InitDiscordAdminChannel sets the channel handler errors are reported to, no reports are posted when it is empty.
+func InitDiscordAdminChannel(channelID string) {
+	adminChannelID = channelID
+}
+
+// InitDiscordRateLimiter sets the limiter checked before every handler, nothing is rate limited when it is nil.
+// InitDiscordRedact sets whether user IDs are pseudonymised in the admin channel, like they are in the log.
+func InitDiscordRedact(redact bool) {
+	redactUsers = redact
+}
+
+func InitDiscordRateLimiter(rateLimiter *ratelimit.Limiter) {
+	limiter = rateLimiter
+}
+
+// InitDiscordDailyEntries sets the most entries a user can add in a day, there is no limit when it is 0.
+func InitDiscordDailyEntries(max int) {
+	maxDailyEntries = max
+}
+
+// WaitForHandlers blocks until every running handler has returned.
+func WaitForHandlers() {
+	inFlight.Wait()
+}
+
+func InitDiscordCommands(cmdDefinitions []*discordgo.ApplicationCommand, cmdHandlers map[string]Handler) {
 	commandDefinitions = cmdDefinitions
 	commandHandlers = cmdHandlers
-	registeredCommands = make([]*discordgo.ApplicationCommand, len(cmdDefinitions))
 }
 
-func InitDiscordComponentHandlers(cmpHandlers map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate)) {
+// InitDiscordComponentHandlers sets the handlers for components and the modals they open, by custom ID prefix.
+func InitDiscordComponentHandlers(cmpHandlers map[string]Handler) {
 	componentHandlers = cmpHandlers
 }
 
+// InitDiscordModalComponents sets the custom ID prefixes of components whose handlers open a modal, which are never
+// deferred as a modal can't follow a deferred response.
+func InitDiscordModalComponents(prefixes []string) {
+	modalComponents = map[string]bool{}
+	for _, prefix := range prefixes {
+		modalComponents[prefix] = true
+	}
+}
+
+// InitDiscordAutocompleteHandlers sets the handlers suggesting option values as users type, by command name.
+func InitDiscordAutocompleteHandlers(acHandlers map[string]Handler) {
+	autocompleteHandlers = acHandlers
+}
+
 func OpenDiscordSession() {
 	err := S.Open()
 	if err != nil {
@@ -40,49 +135,263 @@ func OpenDiscordSession() {
 	}
 }
 
-func AddCommandsDiscord(guildID string) {
-	log.Println("Adding commands...")
-	for i, v := range commandDefinitions {
-		cmd, err := S.ApplicationCommandCreate(S.State.User.ID, guildID, v)
+// RegisterCommandsDiscord makes the registered commands match the command definitions, leaving them alone if they already do.
+func RegisterCommandsDiscord(guildID string) {
+	changed, err := SyncCommands(guildID)
+	if err != nil {
+		log.Panicf("Cannot register commands: %v", err)
+	}
+	if changed {
+		slog.Info("Registered commands", "count", len(commandDefinitions))
+	} else {
+		slog.Info("Commands are already up to date")
+	}
+}
+
+// SyncCommands fetches the registered commands and overwrites them all in one request if they differ from the definitions.
+func SyncCommands(guildID string) (bool, error) {
+	appID, err := applicationID()
+	if err != nil {
+		return false, err
+	}
+
+	existing, err := S.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return false, fmt.Errorf("fetching registered commands: %w", err)
+	}
+	if !commandsChanged(existing, commandDefinitions) {
+		return false, nil
+	}
+
+	if _, err := S.ApplicationCommandBulkOverwrite(appID, guildID, commandDefinitions); err != nil {
+		return false, fmt.Errorf("overwriting commands: %w", err)
+	}
+	return true, nil
+}
+
+func RemoveCommandsDiscord(guildID string) {
+	slog.Info("Removing commands")
+	appID, err := applicationID()
+	if err != nil {
+		log.Panicf("Cannot remove commands: %v", err)
+	}
+	if _, err := S.ApplicationCommandBulkOverwrite(appID, guildID, []*discordgo.ApplicationCommand{}); err != nil {
+		log.Panicf("Cannot remove commands: %v", err)
+	}
+}
+
+// applicationID returns the bot users ID, which is also the application ID. The user is fetched when there is no gateway session to provide it.
+func applicationID() (string, error) {
+	if S.State.User == nil {
+		user, err := S.User("@me")
 		if err != nil {
-			log.Panicf("Cannot create '%v' command: %v", v.Name, err)
+			return "", fmt.Errorf("fetching the bot user: %w", err)
 		}
-		log.Printf("Registered command %v", cmd.Name)
-		registeredCommands[i] = cmd
+		S.State.User = user
 	}
+	return S.State.User.ID, nil
 }
 
-func RemoveCommandsDiscord(guildID string) {
-	log.Println("Removing commands...")
-	for _, v := range registeredCommands {
-		err := S.ApplicationCommandDelete(S.State.User.ID, guildID, v.ID)
+// commandsChanged reports whether the registered commands differ from the definitions in anything Discord stores.
+func commandsChanged(registered, definitions []*discordgo.ApplicationCommand) bool {
+	if len(registered) != len(definitions) {
+		return true
+	}
+
+	byName := make(map[string]*discordgo.ApplicationCommand, len(registered))
+	for _, cmd := range registered {
+		byName[cmd.Name] = cmd
+	}
+	for _, definition := range definitions {
+		cmd, ok := byName[definition.Name]
+		if !ok {
+			return true
+		}
+		got, err := json.Marshal(normaliseCommand(cmd))
 		if err != nil {
-			log.Panicf("Cannot delete '%v' command: %v", v.Name, err)
+			return true
+		}
+		want, err := json.Marshal(normaliseCommand(definition))
+		if err != nil || !bytes.Equal(got, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// normaliseCommand clears the fields Discord fills in and the defaults it leaves out, so definitions compare equal to what was registered.
+func normaliseCommand(cmd *discordgo.ApplicationCommand) discordgo.ApplicationCommand {
+	normalised := *cmd
+	normalised.ID, normalised.ApplicationID, normalised.GuildID, normalised.Version = "", "", "", ""
+	if normalised.Type == 0 {
+		normalised.Type = discordgo.ChatApplicationCommand
+	}
+	if normalised.DMPermission != nil && *normalised.DMPermission {
+		normalised.DMPermission = nil
+	}
+	if normalised.NSFW != nil && !*normalised.NSFW {
+		normalised.NSFW = nil
+	}
+	normalised.Options = normaliseOptions(cmd.Options)
+	return normalised
+}
+
+func normaliseOptions(options []*discordgo.ApplicationCommandOption) []*discordgo.ApplicationCommandOption {
+	if len(options) == 0 {
+		return nil
+	}
+	normalised := make([]*discordgo.ApplicationCommandOption, len(options))
+	for i, option := range options {
+		copied := *option
+		if len(copied.ChannelTypes) == 0 {
+			copied.ChannelTypes = nil
 		}
-		log.Printf("Removed command %v", v.Name)
+		if len(copied.Choices) == 0 {
+			copied.Choices = nil
+		}
+		copied.Options = normaliseOptions(option.Options)
+		normalised[i] = &copied
 	}
+	return normalised
 }
 
 func onReady(s *discordgo.Session, r *discordgo.Ready) {
-	log.Printf("Logged in as: %v#%v", s.State.User.Username, s.State.User.Discriminator)
+	gatewayConnected.Store(true)
+	slog.Info("Logged in", "username", s.State.User.Username, "discriminator", s.State.User.Discriminator)
+}
+
+func onResumed(s *discordgo.Session, r *discordgo.Resumed) {
+	gatewayConnected.Store(true)
+}
+
+func onDisconnect(s *discordgo.Session, d *discordgo.Disconnect) {
+	gatewayConnected.Store(false)
+	slog.Warn("Disconnected from the gateway")
+}
+
+// CheckConnection reports whether interactions can be received, over the gateway or HTTP.
+func CheckConnection(ctx context.Context) error {
+	if servingHTTP.Load() || gatewayConnected.Load() {
+		return nil
+	}
+	return errors.New("not connected to the gateway")
 }
 
 func handleCommands(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	dispatch(s, i)
+}
+
+// dispatch runs the handler for an interaction, however it was received.
+func dispatch(s Session, i *discordgo.InteractionCreate) {
+	logger := interactionLogger(i)
+	if rootCtx.Err() != nil {
+		logger.Warn("Ignoring interaction while shutting down")
+		return
+	}
+
+	inFlight.Add(1)
+	defer inFlight.Done()
+
+	ctx, cancel := InteractionContext(rootCtx, i)
+	defer cancel()
+	ctx = logging.WithLogger(ctx, logger)
+
+	responder := NewDeferringResponder(ctx, s, i)
+	defer responder.Finish()
+
+	c := NewContext(ctx, responder, i, store)
+	var h Handler
+	var ok bool
 	switch i.Type {
 	case discordgo.InteractionApplicationCommand:
-		log.Printf("Handling slash command interaction %v", i.ApplicationCommandData().Name)
-		if h, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
-			h(s, i)
+		h, ok = commandHandlers[c.Name]
+
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		h, ok = autocompleteHandlers[c.Name]
+
+	case discordgo.InteractionMessageComponent, discordgo.InteractionModalSubmit:
+		h, ok = componentHandlers[c.Name]
+		if i.Type == discordgo.InteractionMessageComponent && modalComponents[c.Name] {
+			responder.NoDefer()
 		}
 
+	default:
+		return
+	}
+
+	if !ok {
+		logger.Warn("No handler for interaction")
+		monitoring.ObserveInteraction(c.Kind, "other", monitoring.OutcomeUnknown, 0)
+		return
+	}
+
+	Wrap(h)(c)
+}
+
+// interactionLogger tags every record logged while handling the interaction with a correlation ID and who triggered it.
+func interactionLogger(i *discordgo.InteractionCreate) *slog.Logger {
+	logger := slog.Default().With("correlation_id", logging.NewID(), "interaction_id", i.ID)
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		logger = logger.With("command", i.ApplicationCommandData().Name)
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		logger = logger.With("autocomplete", i.ApplicationCommandData().Name)
 	case discordgo.InteractionMessageComponent:
-		log.Printf("Handling component interaction %v", i.MessageComponentData().CustomID)
-		idPrefix := strings.Split(i.MessageComponentData().CustomID, "_")[0]
-		if h, ok := componentHandlers[idPrefix]; ok {
-			h(s, i)
-		}
+		// Custom IDs can hold food names
+		customID := i.MessageComponentData().CustomID
+		logger = logger.With("component", strings.Split(customID, "_")[0], logging.CustomIDKey, customID)
+	case discordgo.InteractionModalSubmit:
+		customID := i.ModalSubmitData().CustomID
+		logger = logger.With("modal", strings.Split(customID, "_")[0], logging.CustomIDKey, customID)
+	}
+	if user := InvokingUser(i); user != nil {
+		logger = logger.With(logging.UserIDKey, user.ID, logging.UserKey, user.GlobalName)
+	}
+	return logger
+}
+
+// InteractionContext returns a context that limits how long a handler can work on the interaction.
+// Slow handlers are deferred so the deadline is well past the response window, but always leaves some time in case the clocks disagree.
+func InteractionContext(parent context.Context, i *discordgo.InteractionCreate) (context.Context, context.CancelFunc) {
+	now := time.Now()
+	deadline := now.Add(handlerTimeout)
+	if createdDeadline := interactionCreated(i).Add(handlerTimeout); createdDeadline.Before(deadline) {
+		deadline = createdDeadline
 	}
+	if deadline.Before(now.Add(minimumHandling)) {
+		deadline = now.Add(minimumHandling)
+	}
+	return context.WithDeadline(parent, deadline)
+}
 
+// interactionCreated returns when Discord created the interaction, or now if the ID can't be read.
+func interactionCreated(i *discordgo.InteractionCreate) time.Time {
+	created, err := discordgo.SnowflakeTimestamp(i.ID)
+	if err != nil {
+		return time.Now()
+	}
+	return created
+}
+
+// InvokingUser returns the user who triggered the interaction. Member is only set in servers, User is set everywhere else.
+func InvokingUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// CreateAutocompleteResponse suggests the choices for the option being typed, Discord shows at most 25.
+func CreateAutocompleteResponse(choices []*discordgo.ApplicationCommandOptionChoice) *discordgo.InteractionResponse {
+	if len(choices) > 25 {
+		choices = choices[:25]
+	}
+	return &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	}
 }
 
 func CreateInteractionResponse(content string, ephemeral bool, messageComponents []discordgo.MessageComponent) *discordgo.InteractionResponse {
@@ -104,3 +413,15 @@ func CreateInteractionResponse(content string, ephemeral bool, messageComponents
 
 	return interactionResponse
 }
+
+// Truncate shortens the text to at most max characters without splitting a multi-byte character.
+func Truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+
+	for max > 0 && !utf8.RuneStart(text[max]) {
+		max--
+	}
+	return text[:max]
+}
@@ -0,0 +1,122 @@
+package discord
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// snowflake builds an interaction ID created at the given time.
+func snowflake(created time.Time) string {
+	return strconv.FormatInt((created.UnixMilli()-1420070400000)<<22, 10)
+}
+
+func TestInteractionContext(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		created time.Time
+		want    time.Duration
+	}{
+		{name: "fresh interaction", created: now, want: handlerTimeout},
+		{name: "interaction delivered late", created: now.Add(-time.Second), want: handlerTimeout - time.Second},
+		{name: "interaction older than the timeout", created: now.Add(-time.Minute), want: minimumHandling},
+		{name: "interaction from the future", created: now.Add(time.Minute), want: handlerTimeout},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			i := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{ID: snowflake(test.created)}}
+			ctx, cancel := InteractionContext(context.Background(), i)
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Fatalf("context has no deadline")
+			}
+			if got := deadline.Sub(now); got < test.want-50*time.Millisecond || got > test.want+50*time.Millisecond {
+				t.Errorf("deadline in %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInteractionContextFollowsParent(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	ctx, cancelInteraction := InteractionContext(parent, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{ID: snowflake(time.Now())}})
+	defer cancelInteraction()
+
+	cancel()
+	if ctx.Err() != context.Canceled {
+		t.Errorf("interaction context error = %v, want it cancelled with its parent", ctx.Err())
+	}
+}
+
+func TestCommandsChanged(t *testing.T) {
+	minimum := 1.0
+	definitions := func() []*discordgo.ApplicationCommand {
+		return []*discordgo.ApplicationCommand{
+			{
+				Name:        "add",
+				Description: "Add a food item",
+				Options: []*discordgo.ApplicationCommandOption{
+					{Type: discordgo.ApplicationCommandOptionInteger, Name: "calories", Description: "Calories", Required: true, MinValue: &minimum, MaxValue: 5000},
+				},
+			},
+			{Name: "list", Description: "List food logs"},
+		}
+	}
+	// registered returns the definitions the way Discord sends them back, with IDs and defaults filled in
+	registered := func() []*discordgo.ApplicationCommand {
+		dmPermission, nsfw := true, false
+		cmds := definitions()
+		for i, cmd := range cmds {
+			cmd.ID, cmd.ApplicationID, cmd.Version = strconv.Itoa(i), "app", "1"
+			cmd.Type = discordgo.ChatApplicationCommand
+			cmd.DMPermission, cmd.NSFW = &dmPermission, &nsfw
+			for _, option := range cmd.Options {
+				option.Choices = []*discordgo.ApplicationCommandOptionChoice{}
+			}
+		}
+		// Discord doesn't keep the definitions order
+		cmds[0], cmds[1] = cmds[1], cmds[0]
+		return cmds
+	}
+
+	tests := []struct {
+		name    string
+		change  func([]*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand
+		changed bool
+	}{
+		{name: "unchanged", change: func(cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand { return cmds }},
+		{name: "command removed", changed: true, change: func(cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand { return cmds[1:] }},
+		{name: "command renamed", changed: true, change: func(cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+			cmds[0].Name = "log"
+			return cmds
+		}},
+		{name: "description changed", changed: true, change: func(cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+			cmds[1].Description = "Add food"
+			return cmds
+		}},
+		{name: "option limit changed", changed: true, change: func(cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+			cmds[1].Options[0].MaxValue = 9000
+			return cmds
+		}},
+		{name: "option no longer required", changed: true, change: func(cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+			cmds[1].Options[0].Required = false
+			return cmds
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := commandsChanged(test.change(registered()), definitions()); got != test.changed {
+				t.Errorf("changed = %v, want %v", got, test.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Package discordtest has fakes for running command and component handlers without a Discord connection.
+package discordtest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/database"
+)
+
+// Responder records the responses sent by handlers instead of sending them to Discord.
+type Responder struct {
+	Responses []*discordgo.InteractionResponse
+	// Edits, Followups and Deleted record what was done after a deferred response
+	Edits     []*discordgo.WebhookEdit
+	Followups []*discordgo.WebhookParams
+	Deleted   int
+	// ChannelEmbeds holds the embeds sent to each channel
+	ChannelEmbeds map[string][]*discordgo.MessageEmbed
+	// Users are returned by User, any other ID is treated as unknown
+	Users map[string]*discordgo.User
+}
+
+func (r *Responder) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	r.Responses = append(r.Responses, resp)
+	return nil
+}
+
+func (r *Responder) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	r.Edits = append(r.Edits, newresp)
+	return &discordgo.Message{}, nil
+}
+
+func (r *Responder) InteractionResponseDelete(interaction *discordgo.Interaction, options ...discordgo.RequestOption) error {
+	r.Deleted++
+	return nil
+}
+
+func (r *Responder) FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	r.Followups = append(r.Followups, data)
+	return &discordgo.Message{}, nil
+}
+
+func (r *Responder) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	if r.ChannelEmbeds == nil {
+		r.ChannelEmbeds = map[string][]*discordgo.MessageEmbed{}
+	}
+	r.ChannelEmbeds[channelID] = append(r.ChannelEmbeds[channelID], embed)
+	return &discordgo.Message{}, nil
+}
+
+func (r *Responder) User(userID string, options ...discordgo.RequestOption) (*discordgo.User, error) {
+	if user, ok := r.Users[userID]; ok {
+		return user, nil
+	}
+	return nil, fmt.Errorf("unknown user %v", userID)
+}
+
+// Last returns the most recent response, failing the test if there wasn't one.
+func (r *Responder) Last(t testing.TB) *discordgo.InteractionResponse {
+	t.Helper()
+	if len(r.Responses) == 0 {
+		t.Fatalf("no response was sent")
+	}
+	return r.Responses[len(r.Responses)-1]
+}
+
+// NewStore opens an in memory SQLite store at the latest schema, closed when the test finishes.
+func NewStore(t testing.TB) database.Store {
+	t.Helper()
+	store, err := database.Open(":memory:")
+	if err != nil {
+		t.Fatalf("opening store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	if err := database.Migrate(context.Background(), store); err != nil {
+		t.Fatalf("preparing store: %v", err)
+	}
+	return store
+}
+
+// Command builds a slash command interaction sent by the user.
+func Command(user *discordgo.User, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:     "interaction",
+			Type:   discordgo.InteractionApplicationCommand,
+			Member: &discordgo.Member{User: user},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name:    name,
+				Options: options,
+			},
+		},
+	}
+}
+
+// Component builds a button press by the user on the component with the given custom ID.
+func Component(user *discordgo.User, customID string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:     "interaction",
+			Type:   discordgo.InteractionMessageComponent,
+			Member: &discordgo.Member{User: user},
+			Data: discordgo.MessageComponentInteractionData{
+				CustomID:      customID,
+				ComponentType: discordgo.ButtonComponent,
+			},
+		},
+	}
+}
+
+// Autocomplete builds the autocomplete request Discord sends while the user is typing a command option, mark the option
+// being typed with Focused.
+func Autocomplete(user *discordgo.User, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	i := Command(user, name, options...)
+	i.Type = discordgo.InteractionApplicationCommandAutocomplete
+	return i
+}
+
+// ModalSubmit builds the user submitting the modal with the given custom ID, values are keyed by text input custom ID.
+func ModalSubmit(user *discordgo.User, customID string, values map[string]string) *discordgo.InteractionCreate {
+	var rows []discordgo.MessageComponent
+	for inputID, value := range values {
+		rows = append(rows, &discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{&discordgo.TextInput{CustomID: inputID, Value: value}},
+		})
+	}
+
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:     "interaction",
+			Type:   discordgo.InteractionModalSubmit,
+			Member: &discordgo.Member{User: user},
+			Data: discordgo.ModalSubmitInteractionData{
+				CustomID:   customID,
+				Components: rows,
+			},
+		},
+	}
+}
+
+// DirectMessage moves an interaction into a DM, where Discord sends the user without a member.
+// The context is a DM with the bot, or a DM with someone else when the app is installed to the user.
+func DirectMessage(i *discordgo.InteractionCreate, context discordgo.InteractionContextType) *discordgo.InteractionCreate {
+	i.User = i.Member.User
+	i.Member = nil
+	i.Context = context
+	return i
+}
+
+// Option builds a command option, typed the way Discord sends the value. Integers are sent as JSON numbers so arrive as float64.
+func Option(name string, value any) *discordgo.ApplicationCommandInteractionDataOption {
+	option := &discordgo.ApplicationCommandInteractionDataOption{Name: name, Value: value}
+	switch v := value.(type) {
+	case int:
+		option.Type = discordgo.ApplicationCommandOptionInteger
+		option.Value = float64(v)
+	case float64:
+		option.Type = discordgo.ApplicationCommandOptionNumber
+	case bool:
+		option.Type = discordgo.ApplicationCommandOptionBoolean
+	case *discordgo.User:
+		option.Type = discordgo.ApplicationCommandOptionUser
+		option.Value = v.ID
+	default:
+		option.Type = discordgo.ApplicationCommandOptionString
+	}
+	return option
+}
+
+// Focused builds the option the user is typing in an autocomplete request, Discord sends what has been typed as a string.
+func Focused(name string, typed string, optionType discordgo.ApplicationCommandOptionType) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: optionType, Value: typed, Focused: true}
+}
+
+// Subcommand builds a subcommand option holding the given options.
+func Subcommand(name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:    name,
+		Type:    discordgo.ApplicationCommandOptionSubCommand,
+		Options: options,
+	}
+}
+
+// Content returns the message text of a response.
+func Content(resp *discordgo.InteractionResponse) string {
+	if resp.Data == nil {
+		return ""
+	}
+	return resp.Data.Content
+}
+
+// Ephemeral reports whether only the invoking user can see the response.
+func Ephemeral(resp *discordgo.InteractionResponse) bool {
+	return resp.Data != nil && resp.Data.Flags&discordgo.MessageFlagsEphemeral != 0
+}
+
+// Choices returns the names of the choices suggested by an autocomplete response.
+func Choices(resp *discordgo.InteractionResponse) []string {
+	var names []string
+	if resp.Data == nil {
+		return names
+	}
+	for _, choice := range resp.Data.Choices {
+		names = append(names, choice.Name)
+	}
+	return names
+}
+
+// Buttons returns every button in the responses action rows.
+func Buttons(resp *discordgo.InteractionResponse) []discordgo.Button {
+	var buttons []discordgo.Button
+	if resp.Data == nil {
+		return buttons
+	}
+	for _, component := range resp.Data.Components {
+		row, ok := component.(discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, rowComponent := range row.Components {
+			if button, ok := rowComponent.(discordgo.Button); ok {
+				buttons = append(buttons, button)
+			}
+		}
+	}
+	return buttons
+}
+
+// EmbedText joins the title and every field of the responses embeds, for checking what they show.
+func EmbedText(resp *discordgo.InteractionResponse) string {
+	if resp.Data == nil {
+		return ""
+	}
+	var parts []string
+	for _, embed := range resp.Data.Embeds {
+		parts = append(parts, embed.Title, embed.Description)
+		for _, field := range embed.Fields {
+			parts = append(parts, field.Name, field.Value)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
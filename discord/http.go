@@ -0,0 +1,151 @@
+package discord
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Interactions are small and Discord gives up on a reply after 3 seconds, so bigger or slower requests are cut off
+// before they can tie up the server
+const (
+	maxInteractionBytes = 1 << 20
+	readTimeout         = 5 * time.Second
+	writeTimeout        = 10 * time.Second
+	idleTimeout         = time.Minute
+)
+
+// httpSession sends the first response to an interaction as the reply to Discord's HTTP request,
+// anything after that such as editing a deferred response goes through the REST API as usual.
+type httpSession struct {
+	*discordgo.Session
+	responses chan *discordgo.InteractionResponse
+	// replied is closed once the HTTP request has been answered or abandoned
+	replied chan struct{}
+}
+
+func (s *httpSession) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	select {
+	case <-s.replied:
+		return errors.New("the interaction request has already finished")
+	default:
+	}
+
+	select {
+	case s.responses <- resp:
+		return nil
+	default:
+		return errors.New("the interaction has already been responded to")
+	}
+}
+
+// ServeInteractions serves Discord's interactions endpoint at /interactions on addr instead of using the gateway.
+// The bot user is fetched over REST since there is no gateway session to provide it.
+func ServeInteractions(addr string, publicKey ed25519.PublicKey) *http.Server {
+	if _, err := applicationID(); err != nil {
+		log.Fatalf("Cannot serve interactions: %v", err)
+	}
+	servingHTTP.Store(true)
+
+	mux := http.NewServeMux()
+	mux.Handle("/interactions", InteractionsHandler(publicKey))
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: readTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	go func() {
+		slog.Info("Serving interactions", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Cannot serve interactions: %v", err)
+		}
+	}()
+	return server
+}
+
+// InteractionsHandler verifies each request was signed by Discord with the applications public key,
+// then runs the same handlers as the gateway and replies with their first response.
+func InteractionsHandler(publicKey ed25519.PublicKey) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInteractionBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if !discordgo.VerifyInteraction(r, publicKey) {
+			http.Error(w, "invalid request signature", http.StatusUnauthorized)
+			return
+		}
+
+		interaction := &discordgo.Interaction{}
+		if err := json.NewDecoder(r.Body).Decode(interaction); err != nil {
+			http.Error(w, "invalid interaction", http.StatusBadRequest)
+			return
+		}
+
+		// Discord checks the endpoint with pings when it is configured
+		if interaction.Type == discordgo.InteractionPing {
+			writeInteractionResponse(w, &discordgo.InteractionResponse{Type: discordgo.InteractionResponsePong})
+			return
+		}
+
+		session := &httpSession{
+			Session:   S,
+			responses: make(chan *discordgo.InteractionResponse, 1),
+			replied:   make(chan struct{}),
+		}
+		defer close(session.replied)
+
+		handled := make(chan struct{})
+		go func() {
+			defer close(handled)
+			dispatch(session, &discordgo.InteractionCreate{Interaction: interaction})
+		}()
+
+		select {
+		case resp := <-session.responses:
+			writeInteractionResponse(w, resp)
+		case <-handled:
+			select {
+			case resp := <-session.responses:
+				writeInteractionResponse(w, resp)
+			default:
+				slog.Error("No response to interaction", "interaction_id", interaction.ID)
+				http.Error(w, "no response", http.StatusInternalServerError)
+			}
+		case <-r.Context().Done():
+			slog.Warn("Request ended before a response", "interaction_id", interaction.ID)
+		}
+	})
+}
+
+func writeInteractionResponse(w http.ResponseWriter, resp *discordgo.InteractionResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("Could not write interaction response", "error", err)
+	}
+}
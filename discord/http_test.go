@@ -0,0 +1,122 @@
+package discord
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/discord/discordtest"
+)
+
+// signedRequest builds an interactions request signed the way Discord signs them.
+func signedRequest(t *testing.T, key ed25519.PrivateKey, body string) *http.Request {
+	t.Helper()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/interactions", bytes.NewBufferString(body))
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(key, []byte(timestamp+body))))
+	return req
+}
+
+func TestInteractionsHandler(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	InitDiscordStore(discordtest.NewStore(t))
+	InitDiscordCommands(nil, map[string]Handler{
+		"ping": func(c *Context) {
+			c.Respond(CreateInteractionResponse("pong", true, nil))
+		},
+		"silent": func(c *Context) {},
+	})
+	t.Cleanup(func() {
+		InitDiscordCommands(nil, nil)
+		InitDiscordStore(nil)
+	})
+
+	command := func(name string) string {
+		return fmt.Sprintf(`{"id":%q,"type":2,"token":"token","user":{"id":"100"},"data":{"id":"1","name":%q,"type":1}}`, snowflake(time.Now()), name)
+	}
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		status   int
+		response *discordgo.InteractionResponse
+	}{
+		{
+			name:     "ping",
+			req:      signedRequest(t, privateKey, `{"id":"1","type":1}`),
+			status:   http.StatusOK,
+			response: &discordgo.InteractionResponse{Type: discordgo.InteractionResponsePong},
+		},
+		{
+			name:     "command",
+			req:      signedRequest(t, privateKey, command("ping")),
+			status:   http.StatusOK,
+			response: CreateInteractionResponse("pong", true, nil),
+		},
+		{
+			name:   "command without a response",
+			req:    signedRequest(t, privateKey, command("silent")),
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "signed by someone else",
+			req:    signedRequest(t, otherKey, `{"id":"1","type":1}`),
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "unsigned",
+			req:    httptest.NewRequest(http.MethodPost, "/interactions", bytes.NewBufferString(`{"id":"1","type":1}`)),
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "too large",
+			req:    signedRequest(t, privateKey, `{"id":"1","type":1,"padding":"`+strings.Repeat("x", maxInteractionBytes)+`"}`),
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "not a post",
+			req:    httptest.NewRequest(http.MethodGet, "/interactions", nil),
+			status: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			InteractionsHandler(publicKey).ServeHTTP(rec, test.req)
+
+			if rec.Code != test.status {
+				t.Fatalf("status = %d, want %d: %v", rec.Code, test.status, rec.Body.String())
+			}
+			if test.response == nil {
+				return
+			}
+
+			want, err := json.Marshal(test.response)
+			if err != nil {
+				t.Fatalf("encoding response: %v", err)
+			}
+			if got := bytes.TrimSpace(rec.Body.Bytes()); !bytes.Equal(got, want) {
+				t.Errorf("response = %s, want %s", got, want)
+			}
+		})
+	}
+}
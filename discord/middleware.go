@@ -0,0 +1,199 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/monitoring"
+)
+
+// Middleware wraps a handler to do work around it, or to stop it running.
+type Middleware func(Handler) Handler
+
+// middleware runs around every handler, outermost first.
+var middleware = []Middleware{LogInteraction, Metrics, Recover, RateLimit, RegisterUser}
+
+// Chain wraps a handler in the middleware, the first of which runs outermost.
+func Chain(h Handler, middleware ...Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// Wrap wraps a handler in the middleware every interaction goes through.
+func Wrap(h Handler) Handler {
+	return Chain(h, middleware...)
+}
+
+// LogInteraction logs when the handler starts and how long it took.
+func LogInteraction(h Handler) Handler {
+	return func(c *Context) {
+		logger := logging.FromContext(c)
+		switch c.Kind {
+		case "command":
+			logger.Info("Handling slash command")
+		case "autocomplete":
+			// Autocomplete runs on every keystroke
+			logger.Debug("Handling autocomplete")
+		case "modal":
+			logger.Info("Handling modal")
+		default:
+			logger.Info("Handling component")
+		}
+
+		start := time.Now()
+		h(c)
+		logger.Debug("Handled interaction", "duration", time.Since(start))
+	}
+}
+
+// Metrics records the outcome and duration of the handler. It runs outside Recover so it sees panics.
+func Metrics(h Handler) Handler {
+	return func(c *Context) {
+		start := time.Now()
+		h(c)
+
+		outcome := monitoring.OutcomeOK
+		switch {
+		case c.panicked:
+			outcome = monitoring.OutcomePanic
+		case c.Err() == context.DeadlineExceeded:
+			logging.FromContext(c).Warn("Interaction ran past its deadline")
+			outcome = monitoring.OutcomeTimeout
+		case !responded(c.Session):
+			outcome = monitoring.OutcomeNoResponse
+		}
+		monitoring.ObserveInteraction(c.Kind, c.Name, outcome, time.Since(start))
+	}
+}
+
+// responded reports whether the responder has sent a response, assuming it has when it doesn't keep track.
+func responded(s Responder) bool {
+	if tracker, ok := s.(interface{ Responded() bool }); ok {
+		return tracker.Responded()
+	}
+	return true
+}
+
+// RateLimit stops users from using a command or button more often than the limiter allows, telling them when they can try again.
+// Autocomplete isn't limited as it runs on every keystroke and can only be answered with suggestions.
+func RateLimit(h Handler) Handler {
+	return func(c *Context) {
+		if limiter == nil || c.User == nil || c.Kind == "autocomplete" {
+			h(c)
+			return
+		}
+
+		allowed, wait := limiter.Allow(c.User.ID, c.Name)
+		if !allowed {
+			// Round up so users aren't told to wait 0s, and show 1m rather than 1m0s
+			wait = time.Duration(math.Ceil(wait.Seconds())) * time.Second
+			logging.FromContext(c).Info("User was rate limited", "wait", wait)
+			waitText := strings.TrimSuffix(wait.String(), "m0s")
+			if waitText != wait.String() {
+				waitText += "m"
+			}
+
+			action := "pressing that"
+			if c.Kind == "command" {
+				action = "using /" + c.Name
+			}
+			c.Respond(CreateInteractionResponse(fmt.Sprintf("You're %v a bit too fast, try again in %v.", action, waitText), true, nil))
+			return
+		}
+		h(c)
+	}
+}
+
+// RegisterUser loads the invoking users account into the context, creating it the first time they use the bot.
+func RegisterUser(h Handler) Handler {
+	return func(c *Context) {
+		logger := logging.FromContext(c)
+		if c.User == nil {
+			logger.Warn("Interaction has no invoking user")
+			return
+		}
+
+		account, err := c.Store.RegisterUser(c, c.User.ID)
+		if err != nil {
+			logger.Error("Error fetching user", "error", err)
+			if c.Kind == "autocomplete" {
+				c.Respond(CreateAutocompleteResponse(nil))
+			} else {
+				c.Respond(CreateInteractionResponse("Error fetching user, please try again...", true, nil))
+			}
+			return
+		}
+		c.Account = account
+		h(c)
+	}
+}
+
+// RequireDailyCalories stops handlers that add to the log until the user has set their daily calories.
+func RequireDailyCalories(h Handler) Handler {
+	return func(c *Context) {
+		if !c.Account.HasDailyCalories() {
+			logging.FromContext(c).Info("User has not set their daily calories")
+			c.Respond(CreateInteractionResponse("Set your daily calories first using the /set command.", true, nil))
+			return
+		}
+		h(c)
+	}
+}
+
+// LimitDailyEntries stops handlers that add to the log once the user has added the most entries allowed today.
+func LimitDailyEntries(h Handler) Handler {
+	return func(c *Context) {
+		if CheckDailyEntries(c) {
+			h(c)
+		}
+	}
+}
+
+// CheckDailyEntries reports whether the user can add another entry today, telling them when they can't.
+func CheckDailyEntries(c *Context) bool {
+	if maxDailyEntries == 0 {
+		return true
+	}
+
+	// Entries deleted since still count, so deleting and adding them again doesn't get around the limit
+	logger := logging.FromContext(c)
+	added, err := c.Store.CountFoodLogsAddedOn(c, c.User.ID, time.Now())
+	if err != nil {
+		logger.Error("Error counting food logs", "error", err)
+		c.Respond(CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return false
+	}
+
+	if added >= maxDailyEntries {
+		logger.Info("User has reached the daily entry limit", "entries", added)
+		c.Respond(CreateInteractionResponse(fmt.Sprintf("You can add at most %d entries a day, update one instead.", maxDailyEntries), true, nil))
+		return false
+	}
+	return true
+}
+
+// RequireOwner only lets the user whose ID is at the given position of the component or modals custom ID use it,
+// telling anyone else the message instead.
+func RequireOwner(position int, message string) Middleware {
+	return func(h Handler) Handler {
+		return func(c *Context) {
+			var owner string
+			if parts := strings.Split(c.CustomID(), "_"); len(parts) > position {
+				owner = parts[position]
+			}
+
+			if owner != c.User.ID {
+				logging.FromContext(c).Info("User tried to use a component belonging to another user", "owner_user_id", owner)
+				c.Respond(CreateInteractionResponse(message, true, nil))
+				return
+			}
+			h(c)
+		}
+	}
+}
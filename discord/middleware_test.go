@@ -0,0 +1,179 @@
+package discord
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/discord/discordtest"
+	"github.com/discordcalorietracker/ratelimit"
+)
+
+func TestChainRunsMiddlewareInOrder(t *testing.T) {
+	var order []string
+	record := func(name string) Middleware {
+		return func(h Handler) Handler {
+			return func(c *Context) {
+				order = append(order, name)
+				h(c)
+			}
+		}
+	}
+
+	Chain(func(c *Context) { order = append(order, "handler") }, record("first"), record("second"))(&Context{})
+
+	if got := strings.Join(order, ","); got != "first,second,handler" {
+		t.Errorf("order = %v, want first,second,handler", got)
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	alice := &discordgo.User{ID: "100"}
+
+	c := NewContext(context.Background(), &discordtest.Responder{}, discordtest.Command(alice, "add", discordtest.Option("fooditem", "Toast")), nil)
+	if c.Kind != "command" || c.Name != "add" || c.User.ID != alice.ID || c.Options["fooditem"].StringValue() != "Toast" {
+		t.Errorf("command context = %+v, want add from alice with the fooditem option", c)
+	}
+
+	c = NewContext(context.Background(), &discordtest.Responder{}, discordtest.Component(alice, "fldel_100_1_Toast"), nil)
+	if c.Kind != "component" || c.Name != "fldel" || len(c.Options) != 0 {
+		t.Errorf("component context = %+v, want fldel with no options", c)
+	}
+
+	c = NewContext(context.Background(), &discordtest.Responder{}, discordtest.Autocomplete(alice, "update", discordtest.Focused("logid", "ba", discordgo.ApplicationCommandOptionInteger)), nil)
+	if c.Kind != "autocomplete" || c.Name != "update" || !c.Options["logid"].Focused {
+		t.Errorf("autocomplete context = %+v, want update with the focused logid option", c)
+	}
+
+	c = NewContext(context.Background(), &discordtest.Responder{}, discordtest.ModalSubmit(alice, "fleditsave_100_1", map[string]string{"fooditem": "Toast"}), nil)
+	if c.Kind != "modal" || c.Name != "fleditsave" || c.CustomID() != "fleditsave_100_1" || c.ModalValue("fooditem") != "Toast" || c.ModalValue("calories") != "" {
+		t.Errorf("modal context = %+v, want fleditsave with the fooditem value", c)
+	}
+}
+
+func TestRegisterUser(t *testing.T) {
+	alice := &discordgo.User{ID: "100"}
+	store := discordtest.NewStore(t)
+
+	var account string
+	c := NewContext(context.Background(), &discordtest.Responder{}, discordtest.Command(alice, "list"), store)
+	RegisterUser(func(c *Context) { account = c.Account.ID })(c)
+
+	if account != alice.ID {
+		t.Fatalf("account = %q, want %q", account, alice.ID)
+	}
+	if user, err := store.FetchUserByID(context.Background(), alice.ID); err != nil || user.ID != alice.ID || user.HasDailyCalories() {
+		t.Errorf("stored user = %+v, %v, want alice without daily calories", user, err)
+	}
+}
+
+func TestRequireDailyCalories(t *testing.T) {
+	alice := &discordgo.User{ID: "100"}
+	responder := &discordtest.Responder{}
+
+	ran := false
+	c := NewContext(context.Background(), responder, discordtest.Command(alice, "add"), nil)
+	RequireDailyCalories(func(c *Context) { ran = true })(c)
+
+	if ran {
+		t.Errorf("the handler ran without daily calories set")
+	}
+	if got := discordtest.Content(responder.Last(t)); got != "Set your daily calories first using the /set command." {
+		t.Errorf("content = %q, want the set first message", got)
+	}
+}
+
+func TestRequireOwner(t *testing.T) {
+	alice := &discordgo.User{ID: "100"}
+	bob := &discordgo.User{ID: "200"}
+
+	tests := []struct {
+		name        string
+		interaction *discordgo.InteractionCreate
+		runs        bool
+	}{
+		{name: "owner", interaction: discordtest.Component(alice, "fldel_100_1_Toast"), runs: true},
+		{name: "another user", interaction: discordtest.Component(bob, "fldel_100_1_Toast")},
+		{name: "no owner in the custom ID", interaction: discordtest.Component(alice, "fldel")},
+		{name: "not a component", interaction: discordtest.Command(alice, "del")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			responder := &discordtest.Responder{}
+			ran := false
+			handler := Chain(func(c *Context) { ran = true }, RequireOwner(1, "Not yours."))
+			handler(NewContext(context.Background(), responder, test.interaction, nil))
+
+			if ran != test.runs {
+				t.Fatalf("ran = %v, want %v", ran, test.runs)
+			}
+			if !test.runs && discordtest.Content(responder.Last(t)) != "Not yours." {
+				t.Errorf("content = %q, want the message", discordtest.Content(responder.Last(t)))
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	alice := &discordgo.User{ID: "100"}
+	InitDiscordRateLimiter(ratelimit.New(ratelimit.Limit{Burst: 1, Per: time.Minute}, nil))
+	t.Cleanup(func() { InitDiscordRateLimiter(nil) })
+
+	tests := []struct {
+		name        string
+		interaction *discordgo.InteractionCreate
+		content     string
+	}{
+		{name: "command", interaction: discordtest.Command(alice, "add"), content: "You're using /add a bit too fast, try again in 1m."},
+		{name: "button", interaction: discordtest.Component(alice, "flquantity_inc_100_1_Toast"), content: "You're pressing that a bit too fast, try again in 1m."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			responder := &discordtest.Responder{}
+			runs := 0
+			handler := RateLimit(func(c *Context) { runs++ })
+			handler(NewContext(context.Background(), responder, test.interaction, nil))
+			handler(NewContext(context.Background(), responder, test.interaction, nil))
+
+			if runs != 1 {
+				t.Fatalf("ran %d times, want once", runs)
+			}
+			if got := discordtest.Content(responder.Last(t)); got != test.content || !discordtest.Ephemeral(responder.Last(t)) {
+				t.Errorf("content = %q, want ephemeral %q", got, test.content)
+			}
+		})
+	}
+}
+
+func TestLimitDailyEntries(t *testing.T) {
+	alice := &discordgo.User{ID: "100"}
+	store := discordtest.NewStore(t)
+	InitDiscordDailyEntries(2)
+	t.Cleanup(func() { InitDiscordDailyEntries(0) })
+
+	for entry := 0; entry < 2; entry++ {
+		if _, err := store.AddUserFoodLog(context.Background(), &database.FoodLog{UserID: alice.ID, FoodItem: "Toast", Calories: 80, Quantity: 1}); err != nil {
+			t.Fatalf("adding food log: %v", err)
+		}
+	}
+	// Deleted entries still count towards the limit
+	if _, err := store.DeleteUserFoodLog(context.Background(), alice.ID, 1); err != nil {
+		t.Fatalf("deleting food log: %v", err)
+	}
+
+	responder := &discordtest.Responder{}
+	ran := false
+	LimitDailyEntries(func(c *Context) { ran = true })(NewContext(context.Background(), responder, discordtest.Command(alice, "add"), store))
+
+	if ran {
+		t.Errorf("the handler ran with the daily entries used up")
+	}
+	if got := discordtest.Content(responder.Last(t)); got != "You can add at most 2 entries a day, update one instead." {
+		t.Errorf("content = %q, want the daily limit message", got)
+	}
+}
@@ -0,0 +1,106 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/logging"
+)
+
+// Embed fields are limited to 1024 characters, the full stack is in the log
+const maxReportStack = 1000
+
+// Recover reports a panic in the handler under a new error ID instead of crashing the bot.
+// The user is told the ID so it can be matched to the logged stack.
+func Recover(h Handler) Handler {
+	return func(c *Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				c.panicked = true
+				reportPanic(c, c.Session, c.Interaction, r, debug.Stack())
+			}
+		}()
+		h(c)
+	}
+}
+
+func reportPanic(ctx context.Context, s Responder, i *discordgo.InteractionCreate, recovered any, stack []byte) {
+	errorID := logging.NewID()
+	user := InvokingUser(i)
+	userID := ""
+	if user != nil {
+		userID = user.ID
+		if redactUsers {
+			// The admin channel is read by people, so it gets the same hash as the log
+			userID = logging.Pseudonymise(userID)
+		}
+	}
+
+	logger := logging.FromContext(ctx).With("error_id", errorID)
+	logger.Error("Handler panicked",
+		"type", i.Type.String(),
+		"guild_id", i.GuildID,
+		"channel_id", i.ChannelID,
+		"panic", fmt.Sprint(recovered),
+		"stack", string(stack),
+	)
+
+	message := fmt.Sprintf("Something went wrong, please try again. If it keeps happening, report error ID `%v`.", errorID)
+	if responded, ok := s.(interface{ Responded() bool }); ok && responded.Responded() {
+		// The response has already been sent, so the error goes in a message after it
+		if session, ok := s.(Session); ok {
+			if _, err := session.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
+				Content: message,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			}); err != nil {
+				logger.Error("Could not report the error to the user", "error", err)
+			}
+		}
+	} else if err := s.InteractionRespond(i.Interaction, CreateInteractionResponse(message, true, nil)); err != nil {
+		logger.Error("Could not report the error to the user", "error", err)
+	}
+
+	if adminChannelID == "" {
+		return
+	}
+	session, ok := s.(Session)
+	if !ok {
+		return
+	}
+	shortStack := Truncate(strings.TrimSpace(string(stack)), maxReportStack)
+	_, err := session.ChannelMessageSendEmbed(adminChannelID, &discordgo.MessageEmbed{
+		Title:       "Handler error " + errorID,
+		Description: fmt.Sprint(recovered),
+		Color:       0xE74C3C,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Interaction", Value: fmt.Sprintf("%v `%v`", i.Type, interactionName(i)), Inline: true},
+			{Name: "User", Value: userID, Inline: true},
+			{Name: "Guild", Value: orNone(i.GuildID), Inline: true},
+			{Name: "Stack", Value: "```" + shortStack + "```"},
+		},
+	})
+	if err != nil {
+		logger.Error("Could not post the error to the admin channel", "error", err)
+	}
+}
+
+// interactionName returns the command name or component custom ID of an interaction.
+func interactionName(i *discordgo.InteractionCreate) string {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		return i.ApplicationCommandData().Name
+	case discordgo.InteractionMessageComponent:
+		return i.MessageComponentData().CustomID
+	}
+	return ""
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -0,0 +1,146 @@
+package discord
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/discord/discordtest"
+	"github.com/discordcalorietracker/logging"
+)
+
+func TestDispatchRecoversPanics(t *testing.T) {
+	alice := &discordgo.User{ID: "100"}
+
+	tests := []struct {
+		name         string
+		handler      Handler
+		adminChannel string
+		responses    int
+		followups    int
+		reports      int
+	}{
+		{
+			name: "before responding",
+			handler: func(c *Context) {
+				var parts []string
+				_ = parts[1]
+			},
+			responses: 1,
+		},
+		{
+			name: "after responding",
+			handler: func(c *Context) {
+				c.Respond(CreateInteractionResponse("Done", true, nil))
+				panic("after the response")
+			},
+			responses: 1,
+			followups: 1,
+		},
+		{
+			name: "reported to the admin channel",
+			handler: func(c *Context) {
+				panic("reported")
+			},
+			adminChannel: "999",
+			responses:    1,
+			reports:      1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			InitDiscordCommands(nil, map[string]Handler{"boom": test.handler})
+			InitDiscordAdminChannel(test.adminChannel)
+			InitDiscordStore(discordtest.NewStore(t))
+			t.Cleanup(func() {
+				InitDiscordCommands(nil, nil)
+				InitDiscordStore(nil)
+				InitDiscordAdminChannel("")
+			})
+
+			session := &discordtest.Responder{}
+			i := discordtest.Command(alice, "boom")
+			i.ID = snowflake(time.Now())
+			dispatch(session, i)
+
+			if len(session.Responses) != test.responses || len(session.Followups) != test.followups {
+				t.Fatalf("got %d responses and %d followups, want %d and %d", len(session.Responses), len(session.Followups), test.responses, test.followups)
+			}
+
+			var message string
+			if test.followups > 0 {
+				message = session.Followups[0].Content
+			} else {
+				resp := session.Last(t)
+				if !discordtest.Ephemeral(resp) {
+					t.Errorf("the error response is public")
+				}
+				message = discordtest.Content(resp)
+			}
+			if !strings.Contains(message, "error ID") {
+				t.Fatalf("message = %q, want an error ID", message)
+			}
+
+			reports := session.ChannelEmbeds[test.adminChannel]
+			if len(reports) != test.reports {
+				t.Fatalf("got %d admin reports, want %d", len(reports), test.reports)
+			}
+			if test.reports > 0 {
+				errorID := strings.TrimPrefix(reports[0].Title, "Handler error ")
+				if !strings.Contains(message, errorID) {
+					t.Errorf("the user was told %q, but the report is for %v", message, errorID)
+				}
+			}
+		})
+	}
+}
+
+func TestReportPanicRedactsTheUser(t *testing.T) {
+	alice := &discordgo.User{ID: "100"}
+
+	tests := []struct {
+		name   string
+		redact bool
+		want   string
+	}{
+		{"redacted", true, logging.Pseudonymise("100")},
+		{"not redacted", false, "100"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			InitDiscordAdminChannel("999")
+			InitDiscordRedact(test.redact)
+			t.Cleanup(func() {
+				InitDiscordAdminChannel("")
+				InitDiscordRedact(false)
+			})
+
+			session := &discordtest.Responder{}
+			// Multi-byte characters across the cut mustn't be split
+			stack := "x" + strings.Repeat("é", maxReportStack)
+			reportPanic(context.Background(), session, discordtest.Command(alice, "boom"), "boom", []byte(stack))
+
+			reports := session.ChannelEmbeds["999"]
+			if len(reports) != 1 {
+				t.Fatalf("got %d admin reports, want 1", len(reports))
+			}
+			for _, field := range reports[0].Fields {
+				switch field.Name {
+				case "User":
+					if field.Value != test.want {
+						t.Errorf("user = %q, want %q", field.Value, test.want)
+					}
+				case "Stack":
+					if !utf8.ValidString(field.Value) || len(field.Value) > maxReportStack+6 {
+						t.Errorf("stack of %d bytes isn't cut at a character within %d bytes", len(field.Value), maxReportStack)
+					}
+				}
+			}
+		})
+	}
+}
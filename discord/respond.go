@@ -0,0 +1,192 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/logging"
+)
+
+// Session is the part of the Discord session used to respond to interactions, including following up on deferred responses.
+type Session interface {
+	Responder
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	InteractionResponseDelete(interaction *discordgo.Interaction, options ...discordgo.RequestOption) error
+	FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// DeferringResponder acknowledges the interaction with a deferred response if the handler hasn't responded in time,
+// then turns the handlers response into an edit of that acknowledgement. Handlers respond the same way either way.
+type DeferringResponder struct {
+	Session
+	interaction *discordgo.Interaction
+	logger      *slog.Logger
+
+	mu        sync.Mutex
+	timer     *time.Timer
+	deferred  bool
+	responded bool
+	// noDefer is set for handlers that open a modal, as a modal can't follow a deferred response
+	noDefer bool
+}
+
+// NewDeferringResponder sends a deferred acknowledgement if there is no response before Discord's response window is nearly over.
+func NewDeferringResponder(ctx context.Context, s Session, i *discordgo.InteractionCreate) *DeferringResponder {
+	r := &DeferringResponder{Session: s, interaction: i.Interaction, logger: logging.FromContext(ctx)}
+
+	delay := time.Until(interactionCreated(i).Add(deferAfter))
+	if delay < 0 {
+		delay = 0
+	}
+	r.timer = time.AfterFunc(delay, r.Defer)
+	return r
+}
+
+// Defer acknowledges the interaction straight away, for handlers that know their work will be slow.
+// Components keep their message as it is while loading, anything else shows a loading message only the invoking user
+// sees, which the response is moved out of if it isn't ephemeral.
+func (r *DeferringResponder) Defer() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timer.Stop()
+	// Autocomplete can't be deferred, suggestions that are too slow are just dropped
+	if r.deferred || r.responded || r.noDefer || r.interaction.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		return
+	}
+
+	deferral := &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}
+	if r.interaction.Type == discordgo.InteractionMessageComponent {
+		deferral = &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}
+	}
+
+	err := r.Session.InteractionRespond(r.interaction, deferral)
+	if err != nil {
+		r.logger.Error("Could not defer the response", "error", err)
+		return
+	}
+	r.deferred = true
+}
+
+// NoDefer stops the interaction being deferred, for handlers that respond by opening a modal.
+func (r *DeferringResponder) NoDefer() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timer.Stop()
+	r.noDefer = true
+}
+
+// deferredUpdate reports whether the interaction was deferred by a component, which leaves no loading message behind.
+func (r *DeferringResponder) deferredUpdate() bool {
+	return r.deferred && r.interaction.Type == discordgo.InteractionMessageComponent
+}
+
+func (r *DeferringResponder) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timer.Stop()
+	r.responded = true
+	if !r.deferred {
+		return r.Session.InteractionRespond(interaction, resp, options...)
+	}
+
+	// A deferred component can update its message or send a new one
+	if r.deferredUpdate() && resp.Type == discordgo.InteractionResponseUpdateMessage && resp.Data != nil {
+		_, err := r.Session.InteractionResponseEdit(interaction, &discordgo.WebhookEdit{
+			Content:    &resp.Data.Content,
+			Embeds:     &resp.Data.Embeds,
+			Components: &resp.Data.Components,
+		}, options...)
+		return err
+	}
+
+	if resp.Type != discordgo.InteractionResponseChannelMessageWithSource || resp.Data == nil {
+		return errors.New("only message responses can follow a deferred response")
+	}
+
+	if r.deferredUpdate() {
+		_, err := r.Session.FollowupMessageCreate(interaction, true, &discordgo.WebhookParams{
+			Content:    resp.Data.Content,
+			Embeds:     resp.Data.Embeds,
+			Components: resp.Data.Components,
+			Flags:      resp.Data.Flags,
+		}, options...)
+		return err
+	}
+
+	if resp.Data.Flags&discordgo.MessageFlagsEphemeral != 0 {
+		_, err := r.Session.InteractionResponseEdit(interaction, &discordgo.WebhookEdit{
+			Content:    &resp.Data.Content,
+			Embeds:     &resp.Data.Embeds,
+			Components: &resp.Data.Components,
+		}, options...)
+		return err
+	}
+
+	// The deferred response can't be made public, so post the response publicly and remove the loading message
+	_, err := r.Session.FollowupMessageCreate(interaction, true, &discordgo.WebhookParams{
+		Content:    resp.Data.Content,
+		Embeds:     resp.Data.Embeds,
+		Components: resp.Data.Components,
+	}, options...)
+	if err != nil {
+		return err
+	}
+	return r.Session.InteractionResponseDelete(interaction, options...)
+}
+
+// Responded reports whether the handler has already sent its response.
+func (r *DeferringResponder) Responded() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.responded
+}
+
+// Finish stops the deferral timer once the handler has returned, and replaces the loading message if the handler never responded.
+func (r *DeferringResponder) Finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timer.Stop()
+	if !r.deferred || r.responded {
+		return
+	}
+
+	content := "There was an error, please try again..."
+	// Editing a deferred components response would overwrite its message, so the error goes in a new one
+	if r.deferredUpdate() {
+		if _, err := r.Session.FollowupMessageCreate(r.interaction, true, &discordgo.WebhookParams{Content: content, Flags: discordgo.MessageFlagsEphemeral}); err != nil {
+			r.logger.Error("Could not report the missing response", "error", err)
+		}
+		return
+	}
+	if _, err := r.Session.InteractionResponseEdit(r.interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+		r.logger.Error("Could not replace the deferred response", "error", err)
+	}
+}
+
+// Defer acknowledges the interaction straight away if the responder supports deferring, for handlers about to do slow work.
+func Defer(s Responder) {
+	if deferrer, ok := s.(interface{ Defer() }); ok {
+		deferrer.Defer()
+	}
+}
+
+// NoDefer stops the responder deferring the interaction if it supports deferring, for handlers that open a modal.
+func NoDefer(s Responder) {
+	if deferrer, ok := s.(interface{ NoDefer() }); ok {
+		deferrer.NoDefer()
+	}
+}
@@ -0,0 +1,129 @@
+package discord
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/discord/discordtest"
+)
+
+func newTestResponder(created time.Time) (*DeferringResponder, *discordtest.Responder) {
+	session := &discordtest.Responder{}
+	i := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{ID: snowflake(created)}}
+	return NewDeferringResponder(context.Background(), session, i), session
+}
+
+func TestDeferringResponderRespondsDirectly(t *testing.T) {
+	r, session := newTestResponder(time.Now())
+
+	if err := r.InteractionRespond(r.interaction, CreateInteractionResponse("Done", true, nil)); err != nil {
+		t.Fatalf("responding: %v", err)
+	}
+	r.Finish()
+
+	if len(session.Responses) != 1 || session.Responses[0].Type != discordgo.InteractionResponseChannelMessageWithSource {
+		t.Fatalf("responses = %+v, want only the message", session.Responses)
+	}
+	if len(session.Edits) != 0 || len(session.Followups) != 0 {
+		t.Errorf("got edits %+v and followups %+v, want none", session.Edits, session.Followups)
+	}
+}
+
+func TestDeferringResponderDefersSlowHandlers(t *testing.T) {
+	r, session := newTestResponder(time.Now().Add(-deferAfter))
+
+	for waited := time.Duration(0); ; waited += 10 * time.Millisecond {
+		r.mu.Lock()
+		deferred := r.deferred
+		r.mu.Unlock()
+		if deferred {
+			break
+		}
+		if waited > time.Second {
+			t.Fatalf("the response was never deferred")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := r.InteractionRespond(r.interaction, CreateInteractionResponse("Done", true, nil)); err != nil {
+		t.Fatalf("responding: %v", err)
+	}
+	r.Finish()
+
+	if len(session.Responses) != 1 || session.Responses[0].Type != discordgo.InteractionResponseDeferredChannelMessageWithSource {
+		t.Fatalf("responses = %+v, want only the deferred response", session.Responses)
+	}
+	if len(session.Edits) != 1 || *session.Edits[0].Content != "Done" {
+		t.Errorf("edits = %+v, want the message", session.Edits)
+	}
+}
+
+func TestDeferringResponderMovesPublicResponses(t *testing.T) {
+	r, session := newTestResponder(time.Now())
+	Defer(r)
+
+	if err := r.InteractionRespond(r.interaction, CreateInteractionResponse("Done", false, nil)); err != nil {
+		t.Fatalf("responding: %v", err)
+	}
+	r.Finish()
+
+	if len(session.Followups) != 1 || session.Followups[0].Content != "Done" || session.Followups[0].Flags != 0 {
+		t.Errorf("followups = %+v, want the public message", session.Followups)
+	}
+	if session.Deleted != 1 {
+		t.Errorf("deleted %d responses, want the deferred response deleted", session.Deleted)
+	}
+	if len(session.Edits) != 0 {
+		t.Errorf("edits = %+v, want none", session.Edits)
+	}
+}
+
+func TestDeferringResponderReportsMissingResponses(t *testing.T) {
+	r, session := newTestResponder(time.Now())
+	Defer(r)
+	r.Finish()
+
+	if len(session.Edits) != 1 || *session.Edits[0].Content != "There was an error, please try again..." {
+		t.Errorf("edits = %+v, want the error message", session.Edits)
+	}
+}
+
+func TestDeferringResponderSendsModalsAfterDefer(t *testing.T) {
+	r, session := newTestResponder(time.Now())
+	r.interaction.Type = discordgo.InteractionMessageComponent
+	NoDefer(r)
+	Defer(r)
+
+	modal := &discordgo.InteractionResponse{Type: discordgo.InteractionResponseModal, Data: &discordgo.InteractionResponseData{CustomID: "fleditsave_100_1"}}
+	if err := r.InteractionRespond(r.interaction, modal); err != nil {
+		t.Fatalf("responding with a modal: %v", err)
+	}
+	r.Finish()
+
+	if len(session.Responses) != 1 || session.Responses[0].Type != discordgo.InteractionResponseModal {
+		t.Errorf("responses = %+v, want only the modal", session.Responses)
+	}
+}
+
+func TestDeferringResponderDefersComponentsWithAnUpdate(t *testing.T) {
+	r, session := newTestResponder(time.Now())
+	r.interaction.Type = discordgo.InteractionMessageComponent
+	Defer(r)
+
+	if err := r.InteractionRespond(r.interaction, CreateInteractionResponse("Done", true, nil)); err != nil {
+		t.Fatalf("responding: %v", err)
+	}
+	r.Finish()
+
+	if len(session.Responses) != 1 || session.Responses[0].Type != discordgo.InteractionResponseDeferredMessageUpdate {
+		t.Fatalf("responses = %+v, want only the deferred update", session.Responses)
+	}
+	if len(session.Followups) != 1 || session.Followups[0].Content != "Done" || session.Followups[0].Flags != discordgo.MessageFlagsEphemeral {
+		t.Errorf("followups = %+v, want the ephemeral message", session.Followups)
+	}
+	if len(session.Edits) != 0 || session.Deleted != 0 {
+		t.Errorf("got edits %+v and %d deletions, want the components message left alone", session.Edits, session.Deleted)
+	}
+}
@@ -1,67 +1,148 @@
 package helper
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/discordcalorietracker/config"
 	"github.com/discordcalorietracker/database"
 	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/units"
 )
 
-const DATEFORMAT = "02/01/2006"
+var (
+	DATEFORMAT  = "02/01/2006"
+	embedColour = 0x89CFF0
 
-func ConvertOptionsToMap(i *discordgo.InteractionCreate) map[string]*discordgo.ApplicationCommandInteractionDataOption {
-	// Access options in the order provided by the user.
-	options := i.ApplicationCommandData().Options
-	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
-	for _, opt := range options {
-		optionMap[opt.Name] = opt
+	minItemCalories = 1.0
+	maxItemCalories = 5000.0
+
+	// QuantityStep is how much the add and remove buttons change the quantity by
+	QuantityStep = 1.0
+)
+
+// Quantities are stored to two decimal places
+const (
+	minQuantity = 0.01
+	maxQuantity = 1000.0
+)
+
+// MaxFoodItemLength is the longest food item name kept in the log, so every entry can be typed in /update or the edit modal
+const MaxFoodItemLength = 50
+
+// Configure applies the configured date format, embed colour and limits, the settings must already be validated.
+func Configure(display config.Display, limits config.Limits) {
+	DATEFORMAT = display.DateFormat
+	embedColour, _ = display.Colour()
+	maxItemCalories = limits.MaxItemCalories
+	QuantityStep = limits.QuantityStep
+}
+
+// ToKcal converts an energy value entered in the users unit to kcal, reporting false when it is outside the allowed range.
+func ToKcal(value int64, energyUnit string) (int16, bool) {
+	kcal := math.Round(units.ToKcal(float64(value), energyUnit))
+	if kcal < minItemCalories || kcal > maxItemCalories {
+		return 0, false
+	}
+	return int16(kcal), true
+}
+
+// RoundQuantity rounds the quantity to the two decimal places it is stored with, reporting false when it is outside the
+// allowed range.
+func RoundQuantity(quantity float64) (float64, bool) {
+	quantity = math.Round(quantity*100) / 100
+	if quantity < minQuantity || quantity > maxQuantity {
+		return 0, false
+	}
+	return quantity, true
+}
+
+// QuantityOutOfRangeMessage tells the user the allowed range for quantities.
+func QuantityOutOfRangeMessage() string {
+	return fmt.Sprintf("The quantity must be a number between %v and %v.", minQuantity, maxQuantity)
+}
+
+// WeighedFoodItem names a food along with the weight eaten, e.g Banana (118g), shortening the name so the weight still
+// fits in MaxFoodItemLength.
+func WeighedFoodItem(name string, weight string) string {
+	suffix := fmt.Sprintf(" (%s)", weight)
+	return discord.Truncate(name, MaxFoodItemLength-len(suffix)) + suffix
+}
+
+// FormatQuantity writes the quantity without trailing zeros, e.g 2 or 1.5.
+func FormatQuantity(quantity float64) string {
+	return strconv.FormatFloat(math.Round(quantity*100)/100, 'f', -1, 64)
+}
+
+// FoodLogName names the entry along with how much of it was eaten, e.g Toast, x2 Toast or x1.5 Pizza (slice).
+func FoodLogName(foodLog database.FoodLog) string {
+	name := foodLog.FoodItem
+	if foodLog.Quantity != 1 {
+		name = fmt.Sprintf("x%s %s", FormatQuantity(foodLog.Quantity), name)
 	}
-	return optionMap
+	if foodLog.Serving != "" {
+		name = fmt.Sprintf("%s (%s)", name, foodLog.Serving)
+	}
+	return name
+}
+
+// FoodLogCalories is the calories for every serving eaten.
+func FoodLogCalories(foodLog database.FoodLog) float64 {
+	return float64(foodLog.Calories) * foodLog.Quantity
 }
 
-func DisplayFoodLogEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, userId string, userDisplayName string, date time.Time, messageComponents []discordgo.MessageComponent, ephemeral bool) {
-	user, userErr := database.FetchUserByID(userId)
+// OutOfRangeMessage tells the user the allowed range for energy values in their unit.
+func OutOfRangeMessage(energyUnit string) string {
+	return fmt.Sprintf("The value must be between %v and %v.", units.FormatEnergy(minItemCalories, energyUnit), units.FormatEnergy(maxItemCalories, energyUnit))
+}
+
+func DisplayFoodLogEmbed(c *discord.Context, userId string, userDisplayName string, date time.Time, messageComponents []discordgo.MessageComponent, ephemeral bool) {
+	logger := logging.FromContext(c)
+	user, userErr := c.Store.FetchUserByID(c, userId)
 	if userErr != nil {
-		log.Printf("Error fetching user with ID %v and username %v. Error: %v", userId, userDisplayName, userErr)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("Error fetching user, please try again...", true, nil))
+		logger.Error("Error fetching user", "error", userErr)
+		c.Respond(discord.CreateInteractionResponse("Error fetching user, please try again...", true, nil))
 		return
 	}
 
-	log.Printf("Fetching food logs for user %v on date %v.", userDisplayName, date.Format(DATEFORMAT))
-	foodLogs, foodLogErr := database.FetchDailyFoodLogs(userId, date)
+	logger.Debug("Fetching food logs", "date", date.Format(DATEFORMAT))
+	foodLogs, foodLogErr := c.Store.FetchDailyFoodLogs(c, userId, date)
 	if foodLogErr != nil {
-		log.Printf("Error fetching food logs for user %v: %v", userDisplayName, foodLogErr)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("Error fetching food logs, please try again...", true, nil))
+		logger.Error("Error fetching food logs", "error", foodLogErr)
+		c.Respond(discord.CreateInteractionResponse("Error fetching food logs, please try again...", true, nil))
 		return
 	}
 
 	if len(foodLogs) == 0 {
-		log.Printf("User %v has no logs on %v.", userDisplayName, date.Format(DATEFORMAT))
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse(fmt.Sprintf("No logs found for %v on %v.", userDisplayName, date.Format(DATEFORMAT)), true, nil))
+		logger.Info("User has no logs on the date", "date", date.Format(DATEFORMAT))
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("No logs found for %v on %v.", userDisplayName, date.Format(DATEFORMAT)), true, nil))
 		return
 	}
 
 	daysSinceSunday := int(date.Weekday())
 	previousSunday := date.AddDate(0, 0, -daysSinceSunday)
 
-	log.Printf("Previous Sunday was %v and searched for date is %v. Had to subtract %v days.", previousSunday.Format(DATEFORMAT), date.Format(DATEFORMAT), daysSinceSunday)
+	logger.Debug("Found the previous Sunday", "previous_sunday", previousSunday.Format(DATEFORMAT), "date", date.Format(DATEFORMAT), "days_since_sunday", daysSinceSunday)
 
-	consumed, consumedErr := database.FetchConsumedCaloriesForDate(userId, date)
-	remaining, remainingErr := database.FetchRemainingCalories(userId, date)
-	remainingWeek, remainingWeekErr := database.FetchWeeksRemainingCalories(userId, previousSunday, date)
+	consumed, consumedErr := c.Store.FetchConsumedCaloriesForDate(c, userId, date)
+	remaining, remainingErr := c.Store.FetchRemainingCalories(c, userId, date)
+	remainingWeek, remainingWeekErr := c.Store.FetchWeeksRemainingCalories(c, userId, previousSunday, date)
 	if consumedErr != nil || remainingErr != nil || remainingWeekErr != nil {
-		log.Printf("Error fetching consumed or remaining calories for user %v.", userDisplayName)
-		s.InteractionRespond(i.Interaction, discord.CreateInteractionResponse("Error fetching consumed or remaining calories, please try again...", true, nil))
+		logger.Error("Error fetching consumed or remaining calories")
+		c.Respond(discord.CreateInteractionResponse("Error fetching consumed or remaining calories, please try again...", true, nil))
 		return
 	}
 
 	if !ephemeral {
 		updateBtn := discordgo.Button{
-			Emoji: discordgo.ComponentEmoji{
+			Emoji: &discordgo.ComponentEmoji{
 				Name: "♻️",
 			},
 			Label:    "Update",
@@ -72,7 +153,7 @@ func DisplayFoodLogEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, u
 		messageComponents = append(messageComponents, updateBtn)
 	}
 
-	embed := createFoodLogEmbed(userDisplayName, int64(user.DayStreak), date, foodLogs, int64(user.DailyCalories), consumed, remaining, remainingWeek)
+	embed := createFoodLogEmbed(userDisplayName, int64(user.DayStreak), date, foodLogs, int64(user.DailyCalories), consumed, remaining, remainingWeek, user.EnergyUnit)
 	interactionResponse := &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -95,23 +176,26 @@ func DisplayFoodLogEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, u
 		}
 	}
 
-	s.InteractionRespond(i.Interaction, interactionResponse)
+	c.Respond(interactionResponse)
 }
 
-func createFoodLogEmbed(username string, streak int64, date time.Time, foodLogs []database.FoodLog, daily int64, consumed int64, remaining int64, remainingWeek int64) *discordgo.MessageEmbed {
+func createFoodLogEmbed(username string, streak int64, date time.Time, foodLogs []database.FoodLog, daily int64, consumed int64, remaining int64, remainingWeek int64, energyUnit string) *discordgo.MessageEmbed {
+	// Every value is stored in kcal and shown in the users preferred unit
+	energy := func(kcal int64) int64 {
+		return int64(math.Round(units.FromKcal(float64(kcal), energyUnit)))
+	}
+	energyName := "Calories"
+	if energyUnit == units.Kilojoules {
+		energyName = "kJ"
+	}
+
 	var foodItemNames strings.Builder
 	var calories strings.Builder
 	var times strings.Builder
 
 	for _, foodLog := range foodLogs {
-		totalCalories := foodLog.Calories
-		if foodLog.Quantity > 1 {
-			totalCalories = foodLog.Calories * foodLog.Quantity
-			foodItemNames.WriteString(fmt.Sprintf("(%d) x%d %s\n", foodLog.ID, foodLog.Quantity, foodLog.FoodItem))
-		} else {
-			foodItemNames.WriteString(fmt.Sprintf("(%d) %s\n", foodLog.ID, foodLog.FoodItem))
-		}
-		calories.WriteString(fmt.Sprintf("%d\n", totalCalories))
+		foodItemNames.WriteString(fmt.Sprintf("(%d) %s\n", foodLog.ID, FoodLogName(foodLog)))
+		calories.WriteString(fmt.Sprintf("%d\n", energy(int64(math.Round(FoodLogCalories(foodLog))))))
 		times.WriteString(fmt.Sprintf("%s\n", foodLog.DateTime.Format("15:04")))
 	}
 
@@ -122,15 +206,15 @@ func createFoodLogEmbed(username string, streak int64, date time.Time, foodLogs
 		weeklyGoalStr = "Over"
 	}
 
-	stats := fmt.Sprintf("**Total Consumed**: %d\n**Remaining On Day**: %d\n**Calories %s Weekly Goal**: %d\n", consumed, remaining, weeklyGoalStr, remainingWeek)
+	stats := fmt.Sprintf("**Total Consumed**: %d\n**Remaining On Day**: %d\n**%s %s Weekly Goal**: %d\n", energy(consumed), energy(remaining), energyName, weeklyGoalStr, energy(remainingWeek))
 
 	embed := &discordgo.MessageEmbed{
 		Title:  fmt.Sprintf("Food Log - %s (%s)", username, date.Format(DATEFORMAT)),
 		Author: &discordgo.MessageEmbedAuthor{},
-		Color:  0x89CFF0,
+		Color:  embedColour,
 		Fields: []*discordgo.MessageEmbedField{
 			{
-				Value: fmt.Sprintf("**Daily Calories**: %d\n", daily),
+				Value: fmt.Sprintf("**Daily %s**: %d\n", energyName, energy(daily)),
 			},
 			{
 				Value: "\u200b",
@@ -146,7 +230,7 @@ func createFoodLogEmbed(username string, streak int64, date time.Time, foodLogs
 				Inline: true,
 			},
 			{
-				Name:   "Calories",
+				Name:   energyName,
 				Value:  calories.String(),
 				Inline: true,
 			},
@@ -167,30 +251,243 @@ func createFoodLogEmbed(username string, streak int64, date time.Time, foodLogs
 }
 
 func CreateAddRemoveUpdateButtons(userId string, logId int64, foodName string) []discordgo.MessageComponent {
+	// Discord limits custom IDs to 100 characters and button labels to 80, the food name is last in both so only it is cut
+	// short. The buttons say how much they change the quantity by unless it is a whole serving
+	amount := ""
+	if QuantityStep != 1 {
+		amount = FormatQuantity(QuantityStep) + " "
+	}
+
 	return []discordgo.MessageComponent{
 		discordgo.Button{
-			Emoji: discordgo.ComponentEmoji{
+			Emoji: &discordgo.ComponentEmoji{
 				Name: "⬆️",
 			},
-			Label:    fmt.Sprintf("Add %s", foodName),
+			Label:    discord.Truncate(fmt.Sprintf("Add %s%s", amount, foodName), 80),
 			Style:    discordgo.SecondaryButton,
-			CustomID: fmt.Sprintf("flquantity_inc_%s_%d_%s", userId, logId, foodName),
+			CustomID: discord.Truncate(fmt.Sprintf("flquantity_inc_%s_%d_%s", userId, logId, foodName), 100),
 		},
 		discordgo.Button{
-			Emoji: discordgo.ComponentEmoji{
+			Emoji: &discordgo.ComponentEmoji{
 				Name: "⬇️",
 			},
-			Label:    fmt.Sprintf("Remove %s", foodName),
+			Label:    discord.Truncate(fmt.Sprintf("Remove %s%s", amount, foodName), 80),
 			Style:    discordgo.SecondaryButton,
-			CustomID: fmt.Sprintf("flquantity_dec_%s_%d_%s", userId, logId, foodName),
+			CustomID: discord.Truncate(fmt.Sprintf("flquantity_dec_%s_%d_%s", userId, logId, foodName), 100),
 		},
 		discordgo.Button{
-			Emoji: discordgo.ComponentEmoji{
+			Emoji: &discordgo.ComponentEmoji{
+				Name: "✏️",
+			},
+			Label:    "Edit",
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("fledit_%s_%d", userId, logId),
+		},
+		discordgo.Button{
+			Emoji: &discordgo.ComponentEmoji{
 				Name: "🚮",
 			},
-			Label:    fmt.Sprintf("Delete %s", foodName),
+			Label:    discord.Truncate(fmt.Sprintf("Delete %s", foodName), 80),
 			Style:    discordgo.DangerButton,
-			CustomID: fmt.Sprintf("fldel_%s_%d_%s", userId, logId, foodName),
+			CustomID: discord.Truncate(fmt.Sprintf("fldel_%s_%d_%s", userId, logId, foodName), 100),
+		},
+		CreateUndoButton(userId, logId),
+	}
+}
+
+// CreateUndoButton creates a button that undoes the latest change to the food log.
+func CreateUndoButton(userId string, logId int64) discordgo.Button {
+	return discordgo.Button{
+		Emoji: &discordgo.ComponentEmoji{
+			Name: "↩️",
 		},
+		Label:    "Undo",
+		Style:    discordgo.SecondaryButton,
+		CustomID: fmt.Sprintf("undo_%s_%d", userId, logId),
+	}
+}
+
+// ConvertCalories works out the calories per unit from a nutrition label giving calories per X units and the total for the weight eaten.
+func ConvertCalories(units float64, calories float64, weight float64) (float64, float64) {
+	perUnit := calories / units
+	totalCalories := perUnit * weight
+	return perUnit, totalCalories
+}
+
+// AddFoodLogAndUpdateStreak adds the food log and bumps the users daily streak if it is their first log of the day.
+func AddFoodLogAndUpdateStreak(ctx context.Context, store database.Store, user database.User, foodLog *database.FoodLog) (int64, error) {
+	// Names from the nutrition database or a button can be longer than can be typed
+	foodLog.FoodItem = discord.Truncate(foodLog.FoodItem, MaxFoodItemLength)
+
+	id, err := store.AddUserFoodLog(ctx, foodLog)
+	if err != nil {
+		return 0, err
+	}
+
+	lastLogged := user.LastLogged.Format(DATEFORMAT)
+	currentDate := time.Now().Format(DATEFORMAT)
+
+	if lastLogged != currentDate {
+		logging.FromContext(ctx).Debug("Updating the daily streak", "last_logged", lastLogged)
+		n, err := store.UpdateUserStreak(ctx, user.ID)
+		if err != nil {
+			return id, err
+		}
+		if n == 0 {
+			return id, errors.New("no user found to update the streak for")
+		}
+	}
+
+	return id, nil
+}
+
+// CreateQuickLogButton creates a button that adds the proposed food log, along with its serving size, when pressed.
+// Whole servings are remembered as saved foods when added, weighed entries aren't as their calories are for that weight.
+func CreateQuickLogButton(userId string, foodLog *database.FoodLog, weighed bool) discordgo.Button {
+	kind := "s"
+	if weighed {
+		kind = "w"
+	}
+
+	// Discord limits custom IDs to 100 characters and button labels to 80
+	customID := discord.Truncate(fmt.Sprintf("qlog_%s_%d_%s_%s_%s_%s", userId, foodLog.Calories, FormatQuantity(foodLog.Quantity), kind, foodLog.Serving, foodLog.FoodItem), 100)
+	label := discord.Truncate(fmt.Sprintf("Add %s", foodLog.FoodItem), 80)
+
+	return discordgo.Button{
+		Emoji: &discordgo.ComponentEmoji{
+			Name: "➕",
+		},
+		Label:    label,
+		Style:    discordgo.SuccessButton,
+		CustomID: customID,
+	}
+}
+
+// CreateConvLogButton creates a button that adds a converted food to the log and remembers its calories per unit.
+func CreateConvLogButton(userId string, foodLog *database.FoodLog, perUnit float64) discordgo.Button {
+	return discordgo.Button{
+		Emoji: &discordgo.ComponentEmoji{
+			Name: "➕",
+		},
+		Label:    "Add to log",
+		Style:    discordgo.SuccessButton,
+		CustomID: discord.Truncate(fmt.Sprintf("convlog_%s_%d_%.4f_%s", userId, foodLog.Calories, perUnit, foodLog.FoodItem), 100),
+	}
+}
+
+// UndoFoodLogChange undoes the users latest change to the food log, or to any of their food logs when logId is 0, and says what was undone.
+// The user must be the one interacting, their energy unit is read from the context.
+func UndoFoodLogChange(c *discord.Context, userId string, logId int64) {
+	logger := logging.FromContext(c)
+	change, undoErr := c.Store.UndoFoodLogChange(c, userId, logId)
+	if errors.Is(undoErr, database.ErrChangedSince) {
+		logger.Info("Food log changed since the change being undone", "log_id", change.LogID)
+		c.Respond(discord.CreateInteractionResponse(fmt.Sprintf("Entry %d has changed since, so the change can't be undone.", change.LogID), true, nil))
+		return
+	}
+	if undoErr != nil {
+		logger.Error("Error undoing food log change", "log_id", logId, "error", undoErr)
+		c.Respond(discord.CreateInteractionResponse("There was an error, please try again...", true, nil))
+		return
+	}
+
+	if change.ID == 0 {
+		logger.Info("No food log change to undo", "log_id", logId)
+		c.Respond(discord.CreateInteractionResponse("There is nothing left to undo.", true, nil))
+		return
+	}
+
+	// Entries still in the log after the undo get their buttons back
+	var messageComponents []discordgo.MessageComponent
+	if restored := change.Before; restored != nil {
+		messageComponents = []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: CreateAddRemoveUpdateButtons(userId, restored.ID, restored.FoodItem),
+			},
+		}
+	}
+
+	logger.Info("Undid food log change", "log_id", change.LogID, "action", change.Action)
+	// The message already says the change was undone
+	change.Undone = false
+	content := fmt.Sprintf("Undid a change to entry %d: %s.", change.LogID, DescribeChange(change, c.Account.EnergyUnit))
+	c.Respond(discord.CreateInteractionResponse(content, true, messageComponents))
+}
+
+// maxHistoryChanges is how many of the latest changes /history shows, keeping the embed under Discord's size limit.
+const maxHistoryChanges = 20
+
+// CreateHistoryEmbed lists the changes to a food log, oldest first, with Discord timestamps shown in the readers time zone.
+func CreateHistoryEmbed(logId int64, history []database.FoodLogAudit, energyUnit string) *discordgo.MessageEmbed {
+	shown := history
+	if len(shown) > maxHistoryChanges {
+		shown = shown[len(shown)-maxHistoryChanges:]
+	}
+
+	var timeline strings.Builder
+	for _, change := range shown {
+		timeline.WriteString(fmt.Sprintf("<t:%d:f> %s\n", change.ChangedAt.Unix(), DescribeChange(change, energyUnit)))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("History of entry %d", logId),
+		Color:       embedColour,
+		Description: timeline.String(),
+	}
+	if len(shown) < len(history) {
+		embed.Footer = &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Showing the latest %d of %d changes", len(shown), len(history)),
+		}
+	}
+	return embed
+}
+
+// DescribeChange says what a change did to a food log, e.g "Changed Toast (80 calories) to Brown Toast (90 calories)".
+func DescribeChange(change database.FoodLogAudit, energyUnit string) string {
+	var description string
+	switch change.Action {
+	case database.AuditCreate:
+		description = "Added " + describeEntry(change.After, energyUnit)
+	case database.AuditUpdate:
+		description = fmt.Sprintf("Changed %s to %s", describeEntry(change.Before, energyUnit), describeEntry(change.After, energyUnit))
+	case database.AuditQuantity:
+		description = fmt.Sprintf("Quantity changed from %s to %s", FormatQuantity(change.Before.Quantity), FormatQuantity(change.After.Quantity))
+	case database.AuditDelete:
+		description = "Deleted " + describeEntry(change.Before, energyUnit)
+	case database.AuditRestore:
+		description = "Restored " + describeEntry(change.After, energyUnit) + " from the trash"
+	case database.AuditUndo:
+		description = describeUndo(change, energyUnit)
+	}
+
+	if change.Undone {
+		description += " *(undone)*"
+	}
+	return description
+}
+
+func describeUndo(change database.FoodLogAudit, energyUnit string) string {
+	if change.After == nil {
+		return "Undone, moving the entry to the trash"
+	}
+	if change.Before == nil {
+		return "Undone, restoring " + describeEntry(change.After, energyUnit)
+	}
+	return "Undone, back to " + describeEntry(change.After, energyUnit)
+}
+
+func describeEntry(foodLog *database.FoodLog, energyUnit string) string {
+	energy := units.FormatEnergy(float64(foodLog.Calories), energyUnit)
+	name := foodLog.FoodItem
+	if foodLog.Quantity != 1 {
+		name = fmt.Sprintf("x%s %s", FormatQuantity(foodLog.Quantity), name)
+	}
+
+	switch {
+	case foodLog.Serving != "":
+		return fmt.Sprintf("%s (%s per %s)", name, energy, foodLog.Serving)
+	case foodLog.Quantity != 1:
+		return fmt.Sprintf("%s (%s each)", name, energy)
 	}
+	return fmt.Sprintf("%s (%s)", name, energy)
 }
@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/units"
+)
+
+type offProduct struct {
+	Code            string    `json:"code"`
+	ProductName     string    `json:"product_name"`
+	ProductNameEn   string    `json:"product_name_en"`
+	Brands          string    `json:"brands"`
+	ServingQuantity offNumber `json:"serving_quantity"`
+	Nutriments      struct {
+		EnergyKcal100g offNumber `json:"energy-kcal_100g"`
+		Energy100g     offNumber `json:"energy_100g"`
+	} `json:"nutriments"`
+}
+
+// offNumber accepts the numbers and numeric strings that are both used in the Open Food Facts dumps.
+type offNumber float64
+
+func (n *offNumber) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		// Ignore values that are not numbers rather than failing the whole product
+		return nil
+	}
+	*n = offNumber(value)
+	return nil
+}
+
+// ImportOpenFoodFacts loads an Open Food Facts JSONL dump, optionally gzip compressed.
+func ImportOpenFoodFacts(ctx context.Context, store database.Store, path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var input io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return 0, err
+		}
+		defer gzipReader.Close()
+		input = gzipReader
+	}
+
+	return store.ImportNutrition(ctx, "off", func(add func(database.Nutrition) error) error {
+		// Lines can be far longer than the bufio.Scanner limit so read them whole
+		reader := bufio.NewReaderSize(input, 1<<20)
+		for lineNumber := 1; ; lineNumber++ {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				var product offProduct
+				if jsonErr := json.Unmarshal(line, &product); jsonErr != nil {
+					return fmt.Errorf("parsing line %d: %w", lineNumber, jsonErr)
+				}
+
+				if nutrition, ok := product.toNutrition(); ok {
+					if addErr := add(nutrition); addErr != nil {
+						return addErr
+					}
+				}
+			}
+
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	})
+}
+
+func (product offProduct) toNutrition() (database.Nutrition, bool) {
+	name := product.ProductName
+	if name == "" {
+		name = product.ProductNameEn
+	}
+	if name == "" {
+		return database.Nutrition{}, false
+	}
+	if product.Brands != "" {
+		name = fmt.Sprintf("%s (%s)", name, strings.Split(product.Brands, ",")[0])
+	}
+
+	kcal := float64(product.Nutriments.EnergyKcal100g)
+	if kcal <= 0 {
+		kcal = float64(product.Nutriments.Energy100g) / units.KilojoulesPerKcal
+	}
+	if kcal <= 0 {
+		return database.Nutrition{}, false
+	}
+
+	return database.Nutrition{
+		Name:         name,
+		KcalPer100g:  kcal,
+		ServingGrams: float64(product.ServingQuantity),
+		Barcode:      database.NormaliseBarcode(product.Code),
+	}, true
+}
@@ -0,0 +1,71 @@
+package importer
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/units"
+)
+
+var wantOpenFoodFacts = []database.Nutrition{
+	// The first brand is added to the name and kcal is preferred over kJ
+	{Name: "Nutella (Ferrero)", KcalPer100g: 539, ServingGrams: 15, Barcode: "3017620422003"},
+	// The English name is used when there isn't another, and energy only given in kJ is converted
+	{Name: "Cola", KcalPer100g: 176 / units.KilojoulesPerKcal, Barcode: "5000112548167"},
+	// Numbers that aren't numbers are ignored rather than failing the product
+	{Name: "Crisps", KcalPer100g: 2230 / units.KilojoulesPerKcal, Barcode: "4000417025005"},
+}
+
+func TestImportOpenFoodFacts(t *testing.T) {
+	store := &recordingStore{}
+	count, err := ImportOpenFoodFacts(context.Background(), store, filepath.Join("testdata", "openfoodfacts.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.source != "off" || count != 3 {
+		t.Errorf("imported %d rows from %q, want 3 from off", count, store.source)
+	}
+
+	// Products without energy or a name are skipped
+	checkRows(t, store.rows, wantOpenFoodFacts)
+}
+
+func TestImportOpenFoodFactsGzip(t *testing.T) {
+	contents, err := os.ReadFile(filepath.Join("testdata", "openfoodfacts.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "openfoodfacts.jsonl.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := gzip.NewWriter(file)
+	writer.Write(contents)
+	writer.Close()
+	file.Close()
+
+	store := &recordingStore{}
+	if _, err := ImportOpenFoodFacts(context.Background(), store, path); err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, store.rows, wantOpenFoodFacts)
+}
+
+func TestImportOpenFoodFactsBadLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.jsonl")
+	contents := `{"product_name":"Nutella","nutriments":{"energy-kcal_100g":539}}` + "\n{not json\n"
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ImportOpenFoodFacts(context.Background(), &recordingStore{}, path)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ImportOpenFoodFacts() = %v, want an error for line 2", err)
+	}
+}
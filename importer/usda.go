@@ -0,0 +1,177 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/units"
+)
+
+// FoodData Central energy nutrients in order of preference, with how many of their unit make a kcal.
+// Some foods only have energy in kJ.
+var usdaEnergyNutrients = []struct {
+	id      string
+	perKcal float64
+}{
+	{"1008", 1},
+	{"2047", 1},
+	{"2048", 1},
+	{"1062", units.KilojoulesPerKcal},
+}
+
+// ImportUSDA loads an extracted FoodData Central CSV download. The directory must contain food.csv and
+// food_nutrient.csv, food_portion.csv and branded_food.csv are used for serving sizes and barcodes when present.
+func ImportUSDA(ctx context.Context, store database.Store, dir string) (int64, error) {
+	energy, err := readUSDAEnergy(filepath.Join(dir, "food_nutrient.csv"))
+	if err != nil {
+		return 0, err
+	}
+	logging.FromContext(ctx).Info("Read energy values for USDA foods", "count", len(energy))
+
+	servings, err := readUSDAPortions(filepath.Join(dir, "food_portion.csv"))
+	if err != nil {
+		return 0, err
+	}
+
+	barcodes, brandedServings, err := readUSDABranded(filepath.Join(dir, "branded_food.csv"))
+	if err != nil {
+		return 0, err
+	}
+	for fdcID, grams := range brandedServings {
+		servings[fdcID] = grams
+	}
+
+	return store.ImportNutrition(ctx, "usda", func(add func(database.Nutrition) error) error {
+		return readCSV(filepath.Join(dir, "food.csv"), func(row map[string]string) error {
+			fdcID := row["fdc_id"]
+			kcal, ok := energy[fdcID]
+			if !ok || row["description"] == "" {
+				return nil
+			}
+
+			return add(database.Nutrition{
+				Name:         row["description"],
+				KcalPer100g:  kcal,
+				ServingGrams: servings[fdcID],
+				Barcode:      barcodes[fdcID],
+			})
+		})
+	})
+}
+
+func readUSDAEnergy(path string) (map[string]float64, error) {
+	energy := make(map[string]float64)
+	priority := make(map[string]int)
+
+	err := readCSV(path, func(row map[string]string) error {
+		for rank, nutrient := range usdaEnergyNutrients {
+			if row["nutrient_id"] != nutrient.id {
+				continue
+			}
+
+			current, seen := priority[row["fdc_id"]]
+			if seen && current <= rank {
+				return nil
+			}
+
+			amount, err := strconv.ParseFloat(row["amount"], 64)
+			if err != nil || amount < 0 {
+				return nil
+			}
+			energy[row["fdc_id"]] = amount / nutrient.perKcal
+			priority[row["fdc_id"]] = rank
+		}
+		return nil
+	})
+	return energy, err
+}
+
+func readUSDAPortions(path string) (map[string]float64, error) {
+	servings := make(map[string]float64)
+
+	err := readCSV(path, func(row map[string]string) error {
+		if _, seen := servings[row["fdc_id"]]; seen {
+			return nil
+		}
+
+		grams, err := strconv.ParseFloat(row["gram_weight"], 64)
+		if err != nil || grams <= 0 {
+			return nil
+		}
+		servings[row["fdc_id"]] = grams
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return servings, nil
+	}
+	return servings, err
+}
+
+func readUSDABranded(path string) (map[string]string, map[string]float64, error) {
+	barcodes := make(map[string]string)
+	servings := make(map[string]float64)
+
+	err := readCSV(path, func(row map[string]string) error {
+		if barcode := database.NormaliseBarcode(row["gtin_upc"]); barcode != "" {
+			barcodes[row["fdc_id"]] = barcode
+		}
+
+		unit := strings.ToLower(row["serving_size_unit"])
+		grams, err := strconv.ParseFloat(row["serving_size"], 64)
+		if err == nil && grams > 0 && (unit == "g" || unit == "ml") {
+			servings[row["fdc_id"]] = grams
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return barcodes, servings, nil
+	}
+	return barcodes, servings, err
+}
+
+// readCSV calls handle for every row of the file keyed by the header names.
+func readCSV(path string, handle func(row map[string]string) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("reading header of %v: %w", path, err)
+	}
+	header = append([]string(nil), header...)
+
+	row := make(map[string]string, len(header))
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading %v: %w", path, err)
+		}
+
+		for i, name := range header {
+			if i < len(record) {
+				row[name] = record[i]
+			}
+		}
+		if err := handle(row); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package importer
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/discordcalorietracker/database"
+	"github.com/discordcalorietracker/units"
+)
+
+// recordingStore keeps the imported rows instead of writing them to a database.
+type recordingStore struct {
+	database.Store
+	source string
+	rows   []database.Nutrition
+}
+
+func (s *recordingStore) ImportNutrition(ctx context.Context, source string, read func(add func(database.Nutrition) error) error) (int64, error) {
+	s.source = source
+	err := read(func(nutrition database.Nutrition) error {
+		s.rows = append(s.rows, nutrition)
+		return nil
+	})
+	return int64(len(s.rows)), err
+}
+
+func checkRows(t *testing.T, got []database.Nutrition, want []database.Nutrition) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Name != w.Name || math.Abs(g.KcalPer100g-w.KcalPer100g) > 1e-9 || g.ServingGrams != w.ServingGrams || g.Barcode != w.Barcode {
+			t.Errorf("row %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestImportUSDA(t *testing.T) {
+	store := &recordingStore{}
+	count, err := ImportUSDA(context.Background(), store, filepath.Join("testdata", "usda"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.source != "usda" || count != 3 {
+		t.Errorf("imported %d rows from %q, want 3 from usda", count, store.source)
+	}
+
+	// Foods without energy, with only bad energy numbers or without a description are skipped
+	checkRows(t, store.rows, []database.Nutrition{
+		// kcal is preferred over the Atwater values, and the first usable portion is the serving
+		{Name: "Apples, raw", KcalPer100g: 52, ServingGrams: 182},
+		// Energy only given in kJ is converted
+		{Name: "Bread, white", KcalPer100g: 1113 / units.KilojoulesPerKcal, ServingGrams: 25},
+		// kcal is preferred over kJ, and branded foods have their serving and barcode
+		{Name: "Oat drink", KcalPer100g: 46, ServingGrams: 250, Barcode: "7394376616037"},
+	})
+}
+
+func TestImportUSDAWithoutOptionalFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"food.csv", "food_nutrient.csv"} {
+		contents, err := os.ReadFile(filepath.Join("testdata", "usda", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), contents, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := &recordingStore{}
+	if _, err := ImportUSDA(context.Background(), store, dir); err != nil {
+		t.Fatal(err)
+	}
+	checkRows(t, store.rows, []database.Nutrition{
+		{Name: "Apples, raw", KcalPer100g: 52},
+		{Name: "Bread, white", KcalPer100g: 1113 / units.KilojoulesPerKcal},
+		{Name: "Oat drink", KcalPer100g: 46},
+	})
+
+	if _, err := ImportUSDA(context.Background(), &recordingStore{}, t.TempDir()); err == nil {
+		t.Errorf("importing a directory without food_nutrient.csv = nil, want an error")
+	}
+}
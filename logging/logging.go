@@ -0,0 +1,112 @@
+// Package logging sets up the structured logger and carries a logger tagged with the interaction through contexts.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/discordcalorietracker/config"
+)
+
+// Keys of attributes holding personal data, their values are hidden when redaction is enabled.
+const (
+	UserIDKey = "user_id"
+	UserKey   = "user"
+	FoodKey   = "food"
+	// TextKey is free text typed by the user, e.g a /log message or search query
+	TextKey = "text"
+	// CustomIDKey is a components custom ID, which can contain any of the above
+	CustomIDKey = "custom_id"
+)
+
+// redacted maps personal data keys to how their values are hidden.
+// User IDs are replaced by a hash so one users logs can still be followed without recording who they are.
+var redacted = map[string]func(slog.Value) slog.Value{
+	UserIDKey:   pseudonymise,
+	UserKey:     hide,
+	FoodKey:     hide,
+	TextKey:     hide,
+	CustomIDKey: hide,
+}
+
+type contextKey struct{}
+
+// Configure makes a logger with the configured level, format and redaction the default, including for the log package.
+func Configure(cfg config.Logging) error {
+	logger, err := New(os.Stderr, cfg)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// New returns a logger writing to w with the configured level, format and redaction.
+func New(w io.Writer, cfg config.Logging) (*slog.Logger, error) {
+	level, err := cfg.SlogLevel()
+	if err != nil {
+		return nil, err
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	if cfg.Redact {
+		options.ReplaceAttr = redact
+	}
+
+	if cfg.Format == "json" {
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return slog.New(slog.NewTextHandler(w, options)), nil
+}
+
+// WithLogger returns a context carrying the logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger if there isn't one.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// NewID returns a short random ID for correlating log records, e.g every record for one interaction.
+func NewID() string {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if replace, ok := redacted[attr.Key]; ok {
+		attr.Value = replace(attr.Value)
+	} else if strings.HasSuffix(attr.Key, "_"+UserIDKey) {
+		// Other users IDs, e.g the owner of a button
+		attr.Value = pseudonymise(attr.Value)
+	}
+	return attr
+}
+
+// Pseudonymise returns the hash a user ID is logged as when redaction is enabled.
+func Pseudonymise(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:6])
+}
+
+func pseudonymise(value slog.Value) slog.Value {
+	return slog.StringValue(Pseudonymise(value.String()))
+}
+
+func hide(value slog.Value) slog.Value {
+	return slog.StringValue("[redacted]")
+}
@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/discordcalorietracker/config"
+)
+
+func TestRedaction(t *testing.T) {
+	tests := []struct {
+		name   string
+		redact bool
+		want   map[string]string
+	}{
+		{
+			name:   "redacted",
+			redact: true,
+			want: map[string]string{
+				UserIDKey:       hashed("100"),
+				"owner_user_id": hashed("200"),
+				UserKey:         "[redacted]",
+				FoodKey:         "[redacted]",
+				TextKey:         "[redacted]",
+				CustomIDKey:     "[redacted]",
+				"log_id":        "7",
+			},
+		},
+		{
+			name: "not redacted",
+			want: map[string]string{
+				UserIDKey:       "100",
+				"owner_user_id": "200",
+				UserKey:         "Alice",
+				FoodKey:         "toast",
+				TextKey:         "2 toast",
+				CustomIDKey:     "qlog_100_250_2_toast",
+				"log_id":        "7",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger, err := New(&out, config.Logging{Level: "info", Format: "json", Redact: test.redact})
+			if err != nil {
+				t.Fatalf("creating logger: %v", err)
+			}
+
+			logger.Info("Added food log",
+				UserIDKey, "100",
+				"owner_user_id", "200",
+				UserKey, "Alice",
+				FoodKey, "toast",
+				TextKey, "2 toast",
+				CustomIDKey, "qlog_100_250_2_toast",
+				"log_id", "7",
+			)
+
+			var record map[string]any
+			if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+				t.Fatalf("decoding %q: %v", out.String(), err)
+			}
+			for key, want := range test.want {
+				if got := record[key]; got != want {
+					t.Errorf("%v = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func hashed(id string) string {
+	return pseudonymise(slog.StringValue(id)).String()
+}
+
+func TestPseudonymisedIDsAreStable(t *testing.T) {
+	if hashed("100") != hashed("100") {
+		t.Errorf("the same user ID was pseudonymised differently")
+	}
+	if hashed("100") == hashed("200") {
+		t.Errorf("different user IDs were pseudonymised the same")
+	}
+}
+
+func TestLevel(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(&out, config.Logging{Level: "warn", Format: "text"})
+	if err != nil {
+		t.Fatalf("creating logger: %v", err)
+	}
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+	if strings.Contains(out.String(), "hidden") || !strings.Contains(out.String(), "shown") {
+		t.Errorf("got %q, want only the warning", out.String())
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(&out, config.Logging{Level: "info", Format: "text"})
+	if err != nil {
+		t.Fatalf("creating logger: %v", err)
+	}
+
+	ctx := WithLogger(context.Background(), logger.With("correlation_id", "abc"))
+	FromContext(ctx).Info("handled")
+	if !strings.Contains(out.String(), "correlation_id=abc") {
+		t.Errorf("got %q, want the context loggers attributes", out.String())
+	}
+
+	if FromContext(context.Background()) == nil {
+		t.Errorf("no logger without one in the context, want the default")
+	}
+}
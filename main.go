@@ -1,46 +1,178 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/discordcalorietracker/command"
 	"github.com/discordcalorietracker/component"
+	"github.com/discordcalorietracker/config"
 	"github.com/discordcalorietracker/database"
 	"github.com/discordcalorietracker/discord"
+	"github.com/discordcalorietracker/helper"
+	"github.com/discordcalorietracker/logging"
+	"github.com/discordcalorietracker/monitoring"
+	"github.com/discordcalorietracker/ratelimit"
 )
 
 // Bot parameters
+// Flags override the config file and environment variables when they are passed
 var (
+	ConfigPath     = flag.String("config", "", "Path to a YAML config file, can also be set with CALORIEBOT_CONFIG")
 	GuildID        = flag.String("guild", "", "Test guild ID. If not passed - bot registers commands globally")
 	BotToken       = flag.String("token", "", "Bot access token")
-	RemoveCommands = flag.Bool("rmcmd", true, "Remove all commands after shutting down or not")
+	BotTokenFile   = flag.String("token-file", "", "File containing the bot access token, e.g a Docker secret")
+	RemoveCommands = flag.Bool("rmcmd", false, "Remove all commands after shutting down or not")
+	DatabaseDSN    = flag.String("db", "app.db", "SQLite file path or postgres:// connection URL")
+	HTTPListen     = flag.String("http", "", "Serve the interactions endpoint on this address instead of using the gateway, e.g :8080")
 )
 
+// flagSettings maps flag names to the config settings they override.
+var flagSettings = map[string]string{
+	"guild":      "guild_id",
+	"token":      "token",
+	"token-file": "token_file",
+	"rmcmd":      "remove_commands",
+	"db":         "db",
+	"http":       "http_listen",
+}
+
+// loadConfig reads the config file and environment variables, applies any flags that were passed and validates the result.
+func loadConfig(flags *flag.FlagSet, path string) config.Config {
+	cfg, err := config.Load(path)
+	if err != nil {
+		log.Fatalf("Could not load config: %v", err)
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		if key, ok := flagSettings[f.Name]; ok {
+			if err := cfg.Set(key, f.Value.String()); err != nil {
+				log.Fatalf("Invalid -%v flag: %v", f.Name, err)
+			}
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+	if err := logging.Configure(cfg.Logging); err != nil {
+		log.Fatalf("Invalid logging config: %v", err)
+	}
+	return cfg
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			runImport(os.Args[2:])
+			return
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "register":
+			runRegister(os.Args[2:])
+			return
+		}
+	}
+
 	flag.Parse()
+	cfg := loadConfig(flag.CommandLine, *ConfigPath)
+	requireToken(cfg)
+
+	ctx, stop := rootContext()
+	defer stop()
+
+	database.InitDatabase(ctx, cfg.Database.DSN)
+	var monitoringServer *http.Server
+	if cfg.Monitoring.Listen != "" {
+		database.DB = monitoring.InstrumentStore(database.DB)
+		monitoringServer = monitoring.Serve(cfg.Monitoring.Listen, []monitoring.Check{
+			{Name: "discord", Run: discord.CheckConnection},
+			{Name: "database", Run: database.DB.Ping},
+		})
+	}
 
-	database.InitDatabase()
+	go database.PurgeTrashEvery(ctx, database.DB, time.Hour)
 
-	discord.InitDiscordSession(*BotToken)
-	discord.OpenDiscordSession()
+	command.Configure(cfg.Limits)
+	helper.Configure(cfg.Display, cfg.Limits)
+
+	discord.InitDiscordSession(cfg.Discord.Token)
+	discord.InitDiscordStore(database.DB)
+	discord.InitDiscordContext(ctx)
+	discord.InitDiscordAdminChannel(cfg.Discord.AdminChannelID)
+	discord.InitDiscordRedact(cfg.Logging.Redact)
+	discord.InitDiscordRateLimiter(newRateLimiter(cfg.RateLimits))
+	discord.InitDiscordDailyEntries(cfg.Limits.MaxDailyEntries)
+
+	var server *http.Server
+	if cfg.HTTP.Listen != "" {
+		publicKey, _ := cfg.HTTP.Key()
+		server = discord.ServeInteractions(cfg.HTTP.Listen, publicKey)
+	} else {
+		discord.OpenDiscordSession()
+	}
 	discord.InitDiscordCommands(command.CommandDefinitions, command.CommandHandlers)
 	discord.InitDiscordComponentHandlers(component.ComponentHandlers)
-	discord.AddCommandsDiscord(*GuildID)
+	discord.InitDiscordModalComponents(component.ModalComponents)
+	discord.InitDiscordAutocompleteHandlers(command.AutocompleteHandlers)
+	discord.RegisterCommandsDiscord(cfg.Discord.GuildID)
+	monitoring.SetReady(true)
+
+	slog.Info("Press Ctrl+C to exit")
+	<-ctx.Done()
+	// Cancel in flight work before anything it uses is closed
+	stop()
+	monitoring.SetReady(false)
+	slog.Info("Gracefully shutting down")
+
+	if cfg.Discord.RemoveCommands {
+		discord.RemoveCommandsDiscord(cfg.Discord.GuildID)
+	}
+
+	if server != nil {
+		// Let requests already waiting on a response finish
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Could not shut down the interactions server", "error", err)
+		}
+		cancel()
+	}
 
-	defer database.DB.Close()
-	defer discord.S.Close()
+	discord.S.Close()
+	discord.WaitForHandlers()
+	database.DB.Close()
 
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
-	log.Println("Press Ctrl+C to exit")
-	<-stop
+	if monitoringServer != nil {
+		monitoringServer.Close()
+	}
+}
 
-	if *RemoveCommands {
-		discord.RemoveCommandsDiscord(*GuildID)
+// requireToken exits if no bot token was configured.
+func requireToken(cfg config.Config) {
+	if err := cfg.Discord.RequireToken(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
 	}
+}
 
-	log.Println("Gracefully shutting down.")
+// rootContext is cancelled when the process is asked to stop with SIGINT or SIGTERM.
+func rootContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// newRateLimiter creates the limiter for the configured rate limits.
+func newRateLimiter(cfg config.RateLimits) *ratelimit.Limiter {
+	limits := make(map[string]ratelimit.Limit, len(cfg.Commands))
+	for name, limit := range cfg.Commands {
+		limits[name] = ratelimit.Limit{Burst: limit.Burst, Per: limit.Per}
+	}
+	return ratelimit.New(ratelimit.Limit{Burst: cfg.Default.Burst, Per: cfg.Default.Per}, limits)
 }
@@ -0,0 +1,86 @@
+// Package monitoring exposes Prometheus metrics and health checks over HTTP.
+package monitoring
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "calorietracker"
+
+// Outcomes of handling an interaction.
+const (
+	OutcomeOK         = "ok"
+	OutcomePanic      = "panic"
+	OutcomeTimeout    = "timeout"
+	OutcomeNoResponse = "no_response"
+	OutcomeUnknown    = "unknown"
+)
+
+var (
+	// Registry holds every metric served on /metrics.
+	Registry = prometheus.NewRegistry()
+
+	interactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "interactions_total",
+		Help:      "Interactions handled, by kind, command or component name and outcome.",
+	}, []string{"kind", "name", "outcome"})
+
+	interactionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "interaction_duration_seconds",
+		Help:      "Time taken to handle interactions, by kind and command or component name.",
+		// Discord needs a response within 3 seconds, slower handlers are deferred
+		Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2, 3, 5, 10, 30},
+	}, []string{"kind", "name"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time taken by database queries, by store method.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"query"})
+
+	errorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "Errors, by where they happened.",
+	}, []string{"source"})
+)
+
+func init() {
+	Registry.MustRegister(
+		interactions,
+		interactionDuration,
+		queryDuration,
+		errorsTotal,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// ObserveInteraction records a handled interaction. Kind is command or component, name is the command name or custom ID prefix.
+func ObserveInteraction(kind string, name string, outcome string, duration time.Duration) {
+	interactions.WithLabelValues(kind, name, outcome).Inc()
+	interactionDuration.WithLabelValues(kind, name).Observe(duration.Seconds())
+	if outcome != OutcomeOK {
+		RecordError(outcome)
+	}
+}
+
+// RecordError counts an error from the given source, e.g a panic or database query.
+func RecordError(source string) {
+	errorsTotal.WithLabelValues(source).Inc()
+}
+
+// observeQuery records how long a store method took and whether it failed.
+// It is deferred, so takes a pointer to the error the method returns.
+func observeQuery(query string, start time.Time, err *error) {
+	queryDuration.WithLabelValues(query).Observe(time.Since(start).Seconds())
+	if *err != nil {
+		RecordError("database")
+	}
+}
@@ -0,0 +1,106 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/discordcalorietracker/discord/discordtest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func get(t *testing.T, handler http.Handler, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("reading %v: %v", path, err)
+	}
+	return rec.Code, string(body)
+}
+
+func TestHealth(t *testing.T) {
+	healthy := Check{Name: "database", Run: func(ctx context.Context) error { return nil }}
+	unhealthy := Check{Name: "discord", Run: func(ctx context.Context) error { return errors.New("not connected to the gateway") }}
+
+	tests := []struct {
+		name   string
+		checks []Check
+		status int
+		body   string
+	}{
+		{name: "every check passes", checks: []Check{healthy}, status: http.StatusOK, body: "database: ok"},
+		{name: "a check fails", checks: []Check{healthy, unhealthy}, status: http.StatusServiceUnavailable, body: "discord: not connected to the gateway"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, body := get(t, Handler(test.checks), "/healthz")
+			if status != test.status || !strings.Contains(body, test.body) {
+				t.Errorf("got %d %q, want %d containing %q", status, body, test.status, test.body)
+			}
+		})
+	}
+}
+
+func TestReady(t *testing.T) {
+	handler := Handler(nil)
+	t.Cleanup(func() { SetReady(false) })
+
+	if status, _ := get(t, handler, "/readyz"); status != http.StatusServiceUnavailable {
+		t.Errorf("status before starting = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+	SetReady(true)
+	if status, _ := get(t, handler, "/readyz"); status != http.StatusOK {
+		t.Errorf("status once started = %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestInteractionMetrics(t *testing.T) {
+	before := testutil.ToFloat64(errorsTotal.WithLabelValues(OutcomePanic))
+	ObserveInteraction("command", "add", OutcomeOK, 20*time.Millisecond)
+	ObserveInteraction("command", "add", OutcomePanic, 5*time.Millisecond)
+
+	if got := testutil.ToFloat64(interactions.WithLabelValues("command", "add", OutcomeOK)); got != 1 {
+		t.Errorf("ok interactions = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(errorsTotal.WithLabelValues(OutcomePanic)) - before; got != 1 {
+		t.Errorf("panic errors = %v, want 1", got)
+	}
+
+	_, body := get(t, Handler(nil), "/metrics")
+	if !strings.Contains(body, `calorietracker_interaction_duration_seconds_count{kind="command",name="add"} 2`) {
+		t.Errorf("metrics are missing the interaction durations:\n%v", body)
+	}
+}
+
+func TestInstrumentStore(t *testing.T) {
+	store := InstrumentStore(discordtest.NewStore(t))
+	before := testutil.ToFloat64(errorsTotal.WithLabelValues("database"))
+
+	if _, err := store.FetchUserByID(context.Background(), "100"); err != nil {
+		t.Fatalf("fetching user: %v", err)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.FetchUserByID(cancelled, "100"); err == nil {
+		t.Fatalf("fetching user with a cancelled context succeeded")
+	}
+
+	if got := testutil.CollectAndCount(queryDuration, "calorietracker_db_query_duration_seconds"); got == 0 {
+		t.Errorf("no query durations were recorded")
+	}
+	_, body := get(t, Handler(nil), "/metrics")
+	if !strings.Contains(body, `calorietracker_db_query_duration_seconds_count{query="FetchUserByID"} 2`) {
+		t.Errorf("metrics are missing the FetchUserByID durations:\n%v", body)
+	}
+	if got := testutil.ToFloat64(errorsTotal.WithLabelValues("database")) - before; got != 1 {
+		t.Errorf("database errors = %v, want 1", got)
+	}
+}
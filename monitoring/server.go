@@ -0,0 +1,88 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Checks slower than this count as failed
+const checkTimeout = 2 * time.Second
+
+// Scrapes and probes are quick, so slow clients are cut off rather than holding connections open
+const (
+	readTimeout  = 5 * time.Second
+	writeTimeout = 10 * time.Second
+	idleTimeout  = time.Minute
+)
+
+// Check reports whether something the bot depends on is working.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+var ready atomic.Bool
+
+// SetReady marks whether the bot is accepting interactions, reported by /readyz.
+func SetReady(isReady bool) {
+	ready.Store(isReady)
+}
+
+// Serve serves /metrics, /healthz and /readyz on addr. Health fails when any of the checks do.
+func Serve(addr string, checks []Check) *http.Server {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           Handler(checks),
+		ReadHeaderTimeout: readTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	go func() {
+		slog.Info("Serving metrics and health checks", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Cannot serve metrics: %v", err)
+		}
+	}()
+	return server
+}
+
+// Handler routes the metrics and health endpoints.
+func Handler(checks []Check) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		status := http.StatusOK
+		var report string
+		for _, check := range checks {
+			if err := check.Run(ctx); err != nil {
+				status = http.StatusServiceUnavailable
+				report += fmt.Sprintf("%v: %v\n", check.Name, err)
+			} else {
+				report += fmt.Sprintf("%v: ok\n", check.Name)
+			}
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, report)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ready")
+	})
+	return mux
+}
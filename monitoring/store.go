@@ -0,0 +1,178 @@
+package monitoring
+
+import (
+	"context"
+	"time"
+
+	"github.com/discordcalorietracker/database"
+)
+
+// instrumentedStore records the duration and errors of every query made through the store it wraps.
+type instrumentedStore struct {
+	database.Store
+}
+
+// InstrumentStore wraps a store so its queries show up in the metrics.
+func InstrumentStore(store database.Store) database.Store {
+	return instrumentedStore{store}
+}
+
+func (s instrumentedStore) FetchUserByID(ctx context.Context, id string) (_ database.User, err error) {
+	defer observeQuery("FetchUserByID", time.Now(), &err)
+	return s.Store.FetchUserByID(ctx, id)
+}
+
+func (s instrumentedStore) RegisterUser(ctx context.Context, id string) (_ database.User, err error) {
+	defer observeQuery("RegisterUser", time.Now(), &err)
+	return s.Store.RegisterUser(ctx, id)
+}
+
+func (s instrumentedStore) SetUserCalories(ctx context.Context, user *database.User) (err error) {
+	defer observeQuery("SetUserCalories", time.Now(), &err)
+	return s.Store.SetUserCalories(ctx, user)
+}
+
+func (s instrumentedStore) SetUserEnergyUnit(ctx context.Context, userId string, energyUnit string) (_ int64, err error) {
+	defer observeQuery("SetUserEnergyUnit", time.Now(), &err)
+	return s.Store.SetUserEnergyUnit(ctx, userId, energyUnit)
+}
+
+func (s instrumentedStore) UpdateUserStreak(ctx context.Context, userId string) (_ int64, err error) {
+	defer observeQuery("UpdateUserStreak", time.Now(), &err)
+	return s.Store.UpdateUserStreak(ctx, userId)
+}
+
+func (s instrumentedStore) AddUserFoodLog(ctx context.Context, foodLog *database.FoodLog) (_ int64, err error) {
+	defer observeQuery("AddUserFoodLog", time.Now(), &err)
+	return s.Store.AddUserFoodLog(ctx, foodLog)
+}
+
+func (s instrumentedStore) UpdateUserFoodLog(ctx context.Context, foodLog *database.FoodLog) (_ int64, err error) {
+	defer observeQuery("UpdateUserFoodLog", time.Now(), &err)
+	return s.Store.UpdateUserFoodLog(ctx, foodLog)
+}
+
+func (s instrumentedStore) UpdateFoodLogQuantity(ctx context.Context, userId string, logId int64, direction string, step float64) (_ int64, err error) {
+	defer observeQuery("UpdateFoodLogQuantity", time.Now(), &err)
+	return s.Store.UpdateFoodLogQuantity(ctx, userId, logId, direction, step)
+}
+
+func (s instrumentedStore) DeleteUserFoodLog(ctx context.Context, userId string, logId int64) (_ int64, err error) {
+	defer observeQuery("DeleteUserFoodLog", time.Now(), &err)
+	return s.Store.DeleteUserFoodLog(ctx, userId, logId)
+}
+
+func (s instrumentedStore) FetchFoodLog(ctx context.Context, userId string, logId int64) (_ database.FoodLog, err error) {
+	defer observeQuery("FetchFoodLog", time.Now(), &err)
+	return s.Store.FetchFoodLog(ctx, userId, logId)
+}
+
+func (s instrumentedStore) FetchDailyFoodLogs(ctx context.Context, userId string, date time.Time) (_ []database.FoodLog, err error) {
+	defer observeQuery("FetchDailyFoodLogs", time.Now(), &err)
+	return s.Store.FetchDailyFoodLogs(ctx, userId, date)
+}
+
+func (s instrumentedStore) CountFoodLogsAddedOn(ctx context.Context, userId string, date time.Time) (_ int, err error) {
+	defer observeQuery("CountFoodLogsAddedOn", time.Now(), &err)
+	return s.Store.CountFoodLogsAddedOn(ctx, userId, date)
+}
+
+func (s instrumentedStore) SearchRecentFoodLogs(ctx context.Context, userId string, search string, limit int) (_ []database.FoodLog, err error) {
+	defer observeQuery("SearchRecentFoodLogs", time.Now(), &err)
+	return s.Store.SearchRecentFoodLogs(ctx, userId, search, limit)
+}
+
+func (s instrumentedStore) FetchFoodLogHistory(ctx context.Context, userId string, logId int64) (_ []database.FoodLogAudit, err error) {
+	defer observeQuery("FetchFoodLogHistory", time.Now(), &err)
+	return s.Store.FetchFoodLogHistory(ctx, userId, logId)
+}
+
+func (s instrumentedStore) UndoFoodLogChange(ctx context.Context, userId string, logId int64) (_ database.FoodLogAudit, err error) {
+	defer observeQuery("UndoFoodLogChange", time.Now(), &err)
+	return s.Store.UndoFoodLogChange(ctx, userId, logId)
+}
+
+func (s instrumentedStore) FetchTrashedFoodLogs(ctx context.Context, userId string) (_ []database.FoodLog, err error) {
+	defer observeQuery("FetchTrashedFoodLogs", time.Now(), &err)
+	return s.Store.FetchTrashedFoodLogs(ctx, userId)
+}
+
+func (s instrumentedStore) RestoreFoodLog(ctx context.Context, userId string, logId int64) (_ int64, err error) {
+	defer observeQuery("RestoreFoodLog", time.Now(), &err)
+	return s.Store.RestoreFoodLog(ctx, userId, logId)
+}
+
+func (s instrumentedStore) EmptyTrash(ctx context.Context, userId string) (_ int64, err error) {
+	defer observeQuery("EmptyTrash", time.Now(), &err)
+	return s.Store.EmptyTrash(ctx, userId)
+}
+
+func (s instrumentedStore) PurgeTrash(ctx context.Context, deletedBefore time.Time) (_ int64, err error) {
+	defer observeQuery("PurgeTrash", time.Now(), &err)
+	return s.Store.PurgeTrash(ctx, deletedBefore)
+}
+
+func (s instrumentedStore) FetchConsumedCaloriesForDate(ctx context.Context, userId string, date time.Time) (_ int64, err error) {
+	defer observeQuery("FetchConsumedCaloriesForDate", time.Now(), &err)
+	return s.Store.FetchConsumedCaloriesForDate(ctx, userId, date)
+}
+
+func (s instrumentedStore) FetchAverageConsumedCalories(ctx context.Context, userId string, fromDate time.Time) (_ int64, err error) {
+	defer observeQuery("FetchAverageConsumedCalories", time.Now(), &err)
+	return s.Store.FetchAverageConsumedCalories(ctx, userId, fromDate)
+}
+
+func (s instrumentedStore) FetchRemainingCalories(ctx context.Context, userId string, date time.Time) (_ int64, err error) {
+	defer observeQuery("FetchRemainingCalories", time.Now(), &err)
+	return s.Store.FetchRemainingCalories(ctx, userId, date)
+}
+
+func (s instrumentedStore) FetchWeeksRemainingCalories(ctx context.Context, userId string, fromDate time.Time, toDate time.Time) (_ int64, err error) {
+	defer observeQuery("FetchWeeksRemainingCalories", time.Now(), &err)
+	return s.Store.FetchWeeksRemainingCalories(ctx, userId, fromDate, toDate)
+}
+
+func (s instrumentedStore) FetchFoodLogDaysCount(ctx context.Context, userId string) (_ int64, err error) {
+	defer observeQuery("FetchFoodLogDaysCount", time.Now(), &err)
+	return s.Store.FetchFoodLogDaysCount(ctx, userId)
+}
+
+func (s instrumentedStore) FetchSavedFood(ctx context.Context, userId string, name string) (_ database.SavedFood, err error) {
+	defer observeQuery("FetchSavedFood", time.Now(), &err)
+	return s.Store.FetchSavedFood(ctx, userId, name)
+}
+
+func (s instrumentedStore) SaveUserFood(ctx context.Context, savedFood *database.SavedFood) (err error) {
+	defer observeQuery("SaveUserFood", time.Now(), &err)
+	return s.Store.SaveUserFood(ctx, savedFood)
+}
+
+func (s instrumentedStore) CountNutrition(ctx context.Context, source string) (_ int64, err error) {
+	defer observeQuery("CountNutrition", time.Now(), &err)
+	return s.Store.CountNutrition(ctx, source)
+}
+
+func (s instrumentedStore) FetchNutritionByName(ctx context.Context, name string) (_ database.Nutrition, err error) {
+	defer observeQuery("FetchNutritionByName", time.Now(), &err)
+	return s.Store.FetchNutritionByName(ctx, name)
+}
+
+func (s instrumentedStore) FetchNutritionByBarcode(ctx context.Context, code string) (_ database.Nutrition, err error) {
+	defer observeQuery("FetchNutritionByBarcode", time.Now(), &err)
+	return s.Store.FetchNutritionByBarcode(ctx, code)
+}
+
+func (s instrumentedStore) SearchNutrition(ctx context.Context, text string, limit int) (_ []database.Nutrition, err error) {
+	defer observeQuery("SearchNutrition", time.Now(), &err)
+	return s.Store.SearchNutrition(ctx, text, limit)
+}
+
+func (s instrumentedStore) ImportNutrition(ctx context.Context, source string, read func(add func(database.Nutrition) error) error) (_ int64, err error) {
+	defer observeQuery("ImportNutrition", time.Now(), &err)
+	return s.Store.ImportNutrition(ctx, source, read)
+}
+
+func (s instrumentedStore) Ping(ctx context.Context) (err error) {
+	defer observeQuery("Ping", time.Now(), &err)
+	return s.Store.Ping(ctx)
+}
@@ -0,0 +1,276 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/discordcalorietracker/units"
+)
+
+// Item is a single food parsed from a free text quick log, e.g "2 slices toast".
+type Item struct {
+	Quantity float64
+	Unit     string
+	Name     string
+}
+
+var numberWords = map[string]float64{
+	"a":       1,
+	"an":      1,
+	"one":     1,
+	"two":     2,
+	"three":   3,
+	"four":    4,
+	"five":    5,
+	"six":     6,
+	"seven":   7,
+	"eight":   8,
+	"nine":    9,
+	"ten":     10,
+	"eleven":  11,
+	"twelve":  12,
+	"dozen":   12,
+	"half":    0.5,
+	"quarter": 0.25,
+	"½":       0.5,
+	"¼":       0.25,
+	"¾":       0.75,
+}
+
+// unitNames map every accepted spelling of a unit to its normalised name.
+var unitNames = map[string]string{
+	"g":           "g",
+	"gram":        "g",
+	"grams":       "g",
+	"kg":          "kg",
+	"kilo":        "kg",
+	"kilos":       "kg",
+	"oz":          "oz",
+	"ounce":       "oz",
+	"ounces":      "oz",
+	"lb":          "lb",
+	"lbs":         "lb",
+	"pound":       "lb",
+	"pounds":      "lb",
+	"ml":          "ml",
+	"l":           "l",
+	"litre":       "l",
+	"litres":      "l",
+	"liter":       "l",
+	"liters":      "l",
+	"slice":       "slice",
+	"slices":      "slice",
+	"piece":       "piece",
+	"pieces":      "piece",
+	"serving":     "serving",
+	"servings":    "serving",
+	"portion":     "serving",
+	"portions":    "serving",
+	"cup":         "cup",
+	"cups":        "cup",
+	"bowl":        "bowl",
+	"bowls":       "bowl",
+	"glass":       "glass",
+	"glasses":     "glass",
+	"tbsp":        "tbsp",
+	"tablespoon":  "tbsp",
+	"tablespoons": "tbsp",
+	"tsp":         "tsp",
+	"teaspoon":    "tsp",
+	"teaspoons":   "tsp",
+	"handful":     "handful",
+	"handfuls":    "handful",
+}
+
+// Units that describe a weight or volume, cups and spoons are treated as servings instead.
+var weightUnits = map[string]bool{
+	"g":  true,
+	"kg": true,
+	"oz": true,
+	"lb": true,
+	"ml": true,
+	"l":  true,
+}
+
+var separators = []string{",", ";", "+", "&", "\n", " and ", " plus "}
+
+// compoundFoods are single foods with "and" in their name, which isn't split on within them
+var compoundFoods = regexp.MustCompile(`\b(?:mac|macaroni|fish|salt|cheese|sweet|bread|peanut butter|rice|bangers|surf|ham|steak) and (?:cheese|chips|vinegar|onion|sour|butter|jelly|jam|peas|mash|turf|kidney)\b`)
+
+// joined stands in for the spaces around "and" in compound foods while the text is split
+const joined = "\x00"
+
+// Multiplication signs between a quantity and the food, e.g "2 x toast"
+var multipliers = map[string]bool{
+	"x": true,
+	"×": true,
+}
+
+// Parse splits a free text description of a meal into the individual items it mentions.
+func Parse(text string) []Item {
+	text = compoundFoods.ReplaceAllStringFunc(strings.ToLower(text), func(food string) string {
+		return strings.ReplaceAll(food, " ", joined)
+	})
+
+	segments := []string{" " + text + " "}
+	for _, sep := range separators {
+		var split []string
+		for _, segment := range segments {
+			split = append(split, strings.Split(segment, sep)...)
+		}
+		segments = split
+	}
+
+	var items []Item
+	for _, segment := range segments {
+		if item, ok := parseSegment(strings.ReplaceAll(segment, joined, " ")); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Grams returns the weight of the item when its unit is a weight or volume. Volumes are treated as 1g per ml.
+func (item Item) Grams() (float64, bool) {
+	if !weightUnits[item.Unit] {
+		return 0, false
+	}
+	return item.Quantity * units.Units[item.Unit].Base, true
+}
+
+// Serving returns the unit when it describes a serving, such as a slice or a cup, rather than a weight.
+func (item Item) Serving() string {
+	if weightUnits[item.Unit] {
+		return ""
+	}
+	return item.Unit
+}
+
+// Singular returns the item name with a trailing plural "s" removed, e.g "bananas" becomes "banana".
+func (item Item) Singular() string {
+	switch {
+	case strings.HasSuffix(item.Name, "ies"):
+		return strings.TrimSuffix(item.Name, "ies") + "y"
+	case strings.HasSuffix(item.Name, "ss"):
+		return item.Name
+	case strings.HasSuffix(item.Name, "s"):
+		return strings.TrimSuffix(item.Name, "s")
+	}
+	return item.Name
+}
+
+func parseSegment(segment string) (Item, bool) {
+	words := strings.FieldsFunc(segment, func(r rune) bool {
+		return unicode.IsSpace(r)
+	})
+
+	item := Item{Quantity: 1}
+	quantityFound := false
+
+	// Leading quantities, e.g "2", "1.5", "1/2", "half a" or "150g"
+	for len(words) > 0 {
+		quantity, unit, ok := parseQuantity(words[0])
+		if !ok {
+			// A food can't start with a broken number such as 1.5.2, so there is nothing sensible to log
+			if malformedNumber(words[0]) {
+				return Item{}, false
+			}
+			break
+		}
+		if quantityFound && quantity == 1 && (words[0] == "a" || words[0] == "an") {
+			// "half a pizza" should stay at half
+		} else if quantityFound {
+			item.Quantity *= quantity
+		} else {
+			item.Quantity = quantity
+		}
+		quantityFound = true
+		words = words[1:]
+		if unit != "" {
+			item.Unit = unit
+			break
+		}
+	}
+
+	if quantityFound && len(words) > 1 && multipliers[words[0]] {
+		words = words[1:]
+	}
+
+	if item.Unit == "" && len(words) > 1 {
+		if unit, ok := unitNames[words[0]]; ok {
+			item.Unit = unit
+			words = words[1:]
+		}
+	}
+
+	if len(words) > 1 && words[0] == "of" {
+		words = words[1:]
+	}
+
+	item.Name = strings.TrimFunc(strings.Join(words, " "), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if item.Name == "" || item.Quantity <= 0 {
+		return Item{}, false
+	}
+	return item, true
+}
+
+// parseQuantity reads a number word, decimal, fraction or a number directly followed by a unit such as "150g".
+func parseQuantity(word string) (float64, string, bool) {
+	if quantity, ok := numberWords[word]; ok {
+		return quantity, "", true
+	}
+
+	if numerator, denominator, ok := strings.Cut(word, "/"); ok {
+		n, nErr := strconv.ParseFloat(numerator, 64)
+		d, dErr := strconv.ParseFloat(denominator, 64)
+		if nErr != nil || dErr != nil || d == 0 {
+			return 0, "", false
+		}
+		return n / d, "", true
+	}
+
+	end := strings.IndexFunc(word, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if end == -1 {
+		end = len(word)
+	}
+	if end == 0 {
+		return 0, "", false
+	}
+
+	quantity, err := strconv.ParseFloat(word[:end], 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	suffix := word[end:]
+	if suffix == "" || multipliers[suffix] {
+		return quantity, "", true
+	}
+	if unit, ok := unitNames[suffix]; ok {
+		return quantity, unit, true
+	}
+	return 0, "", false
+}
+
+// malformedNumber reports whether the word starts with something that looks like a number but isn't one, e.g 1.5.2.
+func malformedNumber(word string) bool {
+	end := strings.IndexFunc(word, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if end == -1 {
+		end = len(word)
+	}
+
+	number := word[:end]
+	if !strings.ContainsFunc(number, unicode.IsDigit) {
+		return false
+	}
+	_, err := strconv.ParseFloat(number, 64)
+	return err != nil
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want []Item
+	}{
+		{"a banana", []Item{{Quantity: 1, Name: "banana"}}},
+		{"2 slices toast and a banana", []Item{{Quantity: 2, Unit: "slice", Name: "toast"}, {Quantity: 1, Name: "banana"}}},
+		{"2 x toast", []Item{{Quantity: 2, Name: "toast"}}},
+		{"2x toast", []Item{{Quantity: 2, Name: "toast"}}},
+		{"3 × eggs", []Item{{Quantity: 3, Name: "eggs"}}},
+		{"150g chicken, 1/2 cup rice", []Item{{Quantity: 150, Unit: "g", Name: "chicken"}, {Quantity: 0.5, Unit: "cup", Name: "rice"}}},
+		{"half a pizza", []Item{{Quantity: 0.5, Name: "pizza"}}},
+		{"1.5 bowls of porridge", []Item{{Quantity: 1.5, Unit: "bowl", Name: "porridge"}}},
+		{"Mac and Cheese", []Item{{Quantity: 1, Name: "mac and cheese"}}},
+		{"fish and chips plus 2 beers", []Item{{Quantity: 1, Name: "fish and chips"}, {Quantity: 2, Name: "beers"}}},
+		{"toast & jam", []Item{{Quantity: 1, Name: "toast"}, {Quantity: 1, Name: "jam"}}},
+		{"1.5.2 apples", nil},
+		{"7up", []Item{{Quantity: 1, Name: "7up"}}},
+		{"0 apples", nil},
+		{", ;", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			if got := Parse(test.text); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestItem(t *testing.T) {
+	tests := []struct {
+		item     Item
+		grams    float64
+		isWeight bool
+		serving  string
+		singular string
+	}{
+		{Item{Quantity: 2, Unit: "slice", Name: "slices"}, 0, false, "slice", "slice"},
+		{Item{Quantity: 150, Unit: "g", Name: "chicken"}, 150, true, "", "chicken"},
+		{Item{Quantity: 2, Unit: "kg", Name: "rice"}, 2000, true, "", "rice"},
+		{Item{Quantity: 1, Name: "berries"}, 0, false, "", "berry"},
+		{Item{Quantity: 1, Name: "glass"}, 0, false, "", "glass"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.item.Name, func(t *testing.T) {
+			grams, isWeight := test.item.Grams()
+			if grams != test.grams || isWeight != test.isWeight {
+				t.Errorf("Grams() = %v, %v, want %v, %v", grams, isWeight, test.grams, test.isWeight)
+			}
+			if got := test.item.Serving(); got != test.serving {
+				t.Errorf("Serving() = %q, want %q", got, test.serving)
+			}
+			if got := test.item.Singular(); got != test.singular {
+				t.Errorf("Singular() = %q, want %q", got, test.singular)
+			}
+		})
+	}
+}
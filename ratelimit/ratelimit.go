@@ -0,0 +1,100 @@
+// Package ratelimit limits how often each user can use each command with token buckets.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Buckets that have been full for this long are forgotten
+const sweepEvery = time.Minute
+
+// Limit lets a user make Burst uses at once, getting one back every Per. A zero limit is unlimited.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+func (l Limit) unlimited() bool {
+	return l.Burst <= 0 || l.Per <= 0
+}
+
+type key struct {
+	userID string
+	name   string
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter holds a token bucket for every user and command they have used recently.
+type Limiter struct {
+	defaultLimit Limit
+	limits       map[string]Limit
+	now          func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[key]*bucket
+	lastSweep time.Time
+}
+
+// New creates a limiter using the limit for each command or component name, and the default limit for any other.
+func New(defaultLimit Limit, limits map[string]Limit) *Limiter {
+	return &Limiter{
+		defaultLimit: defaultLimit,
+		limits:       limits,
+		now:          time.Now,
+		buckets:      map[key]*bucket{},
+	}
+}
+
+// Allow takes a token from the users bucket for the name. When the bucket is empty it returns false and how long until there is a token.
+func (l *Limiter) Allow(userID string, name string) (bool, time.Duration) {
+	limit, ok := l.limits[name]
+	if !ok {
+		limit = l.defaultLimit
+	}
+	if limit.unlimited() {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key{userID, name}]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		l.buckets[key{userID, name}] = b
+	}
+
+	b.tokens = min(float64(limit.Burst), b.tokens+float64(now.Sub(b.updated))/float64(limit.Per))
+	b.updated = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(limit.Per))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep forgets the buckets that have refilled, they would be recreated full anyway.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepEvery {
+		return
+	}
+	l.lastSweep = now
+
+	for k, b := range l.buckets {
+		limit, ok := l.limits[k.name]
+		if !ok {
+			limit = l.defaultLimit
+		}
+		if b.tokens+float64(now.Sub(b.updated))/float64(limit.Per) >= float64(limit.Burst) {
+			delete(l.buckets, k)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestLimiter(defaultLimit Limit, limits map[string]Limit) (*Limiter, *time.Time) {
+	now := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	l := New(defaultLimit, limits)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestAllowUsesUpTheBurst(t *testing.T) {
+	l, _ := newTestLimiter(Limit{Burst: 3, Per: time.Second}, nil)
+
+	for use := 1; use <= 3; use++ {
+		if ok, _ := l.Allow("100", "add"); !ok {
+			t.Fatalf("use %d was limited, want the burst of 3 allowed", use)
+		}
+	}
+	ok, wait := l.Allow("100", "add")
+	if ok || wait != time.Second {
+		t.Errorf("fourth use = %v, %v, want limited for 1s", ok, wait)
+	}
+}
+
+func TestAllowRefills(t *testing.T) {
+	l, now := newTestLimiter(Limit{Burst: 1, Per: 10 * time.Second}, nil)
+
+	l.Allow("100", "add")
+	*now = now.Add(4 * time.Second)
+	if ok, wait := l.Allow("100", "add"); ok || wait != 6*time.Second {
+		t.Errorf("after 4s = %v, %v, want limited for another 6s", ok, wait)
+	}
+
+	*now = now.Add(6 * time.Second)
+	if ok, _ := l.Allow("100", "add"); !ok {
+		t.Errorf("after 10s the use was limited, want a token back")
+	}
+}
+
+func TestAllowKeysByUserAndName(t *testing.T) {
+	l, _ := newTestLimiter(Limit{Burst: 1, Per: time.Minute}, map[string]Limit{"list": {}})
+
+	l.Allow("100", "add")
+	if ok, _ := l.Allow("200", "add"); !ok {
+		t.Errorf("another user was limited")
+	}
+	if ok, _ := l.Allow("100", "del"); !ok {
+		t.Errorf("another command was limited")
+	}
+	for use := 0; use < 10; use++ {
+		if ok, _ := l.Allow("100", "list"); !ok {
+			t.Fatalf("an unlimited command was limited")
+		}
+	}
+}
+
+func TestSweepForgetsFullBuckets(t *testing.T) {
+	l, now := newTestLimiter(Limit{Burst: 2, Per: time.Second}, nil)
+
+	l.Allow("100", "add")
+	*now = now.Add(sweepEvery)
+	l.Allow("200", "add")
+
+	if _, ok := l.buckets[key{"100", "add"}]; ok {
+		t.Errorf("the refilled bucket was kept")
+	}
+	if _, ok := l.buckets[key{"200", "add"}]; !ok {
+		t.Errorf("the bucket in use was forgotten")
+	}
+}
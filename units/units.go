@@ -0,0 +1,138 @@
+package units
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const KilojoulesPerKcal = 4.184
+
+// Energy units a user can choose to see and enter values in.
+const (
+	Kcal       = "kcal"
+	Kilojoules = "kj"
+)
+
+type Kind int
+
+const (
+	Mass Kind = iota
+	Volume
+)
+
+type Unit struct {
+	Name string
+	Kind Kind
+	// Grams for mass units and millilitres for volume units
+	Base float64
+}
+
+// Units supported for conversions, keyed by their name.
+var Units = map[string]Unit{
+	"g":    {Name: "g", Kind: Mass, Base: 1},
+	"kg":   {Name: "kg", Kind: Mass, Base: 1000},
+	"oz":   {Name: "oz", Kind: Mass, Base: 28.349523125},
+	"lb":   {Name: "lb", Kind: Mass, Base: 453.59237},
+	"ml":   {Name: "ml", Kind: Volume, Base: 1},
+	"l":    {Name: "l", Kind: Volume, Base: 1000},
+	"cup":  {Name: "cup", Kind: Volume, Base: 240},
+	"tbsp": {Name: "tbsp", Kind: Volume, Base: 15},
+	"tsp":  {Name: "tsp", Kind: Volume, Base: 5},
+	"floz": {Name: "fl oz", Kind: Volume, Base: 29.5735295625},
+}
+
+// Densities in grams per millilitre for foods commonly measured by volume.
+var densities = []struct {
+	food    string
+	density float64
+}{
+	{"peanut butter", 1.08},
+	{"olive oil", 0.91},
+	{"oil", 0.92},
+	{"milk", 1.03},
+	{"cream", 1.01},
+	{"yoghurt", 1.03},
+	{"yogurt", 1.03},
+	{"honey", 1.42},
+	{"syrup", 1.33},
+	{"butter", 0.96},
+	{"flour", 0.53},
+	{"brown sugar", 0.93},
+	{"icing sugar", 0.56},
+	{"sugar", 0.85},
+	{"oats", 0.41},
+	{"rice", 0.85},
+	{"juice", 1.04},
+	{"water", 1},
+	{"wine", 0.99},
+	{"beer", 1.01},
+	{"cola", 1.04},
+	{"soup", 1},
+}
+
+// DensityFor returns the grams per millilitre of the food when it is known.
+func DensityFor(food string) (float64, bool) {
+	food = strings.ToLower(food)
+	for _, entry := range densities {
+		if strings.Contains(food, entry.food) {
+			return entry.density, true
+		}
+	}
+	return 0, false
+}
+
+// Convert converts an amount between two units. Converting between mass and volume needs the density of the food in grams per millilitre.
+func Convert(amount float64, from string, to string, density float64) (float64, error) {
+	fromUnit, ok := Units[from]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", from)
+	}
+	toUnit, ok := Units[to]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", to)
+	}
+
+	base := amount * fromUnit.Base
+	if fromUnit.Kind != toUnit.Kind {
+		if density <= 0 {
+			return 0, fmt.Errorf("converting %v to %v needs the density of the food", fromUnit.Name, toUnit.Name)
+		}
+		if fromUnit.Kind == Volume {
+			base *= density
+		} else {
+			base /= density
+		}
+	}
+
+	return base / toUnit.Base, nil
+}
+
+// ToKcal converts an energy value in the given unit, either kcal or kJ, to kcal.
+func ToKcal(energy float64, unit string) float64 {
+	if strings.EqualFold(unit, Kilojoules) {
+		return energy / KilojoulesPerKcal
+	}
+	return energy
+}
+
+// FromKcal converts an energy value in kcal to the given unit, either kcal or kJ.
+func FromKcal(kcal float64, unit string) float64 {
+	if strings.EqualFold(unit, Kilojoules) {
+		return kcal * KilojoulesPerKcal
+	}
+	return kcal
+}
+
+// EnergyName returns how energy in the unit is named in responses.
+func EnergyName(unit string) string {
+	if strings.EqualFold(unit, Kilojoules) {
+		return "kJ"
+	}
+	return "calories"
+}
+
+// FormatEnergy formats a kcal value in the given unit, e.g "250 calories" or "1046 kJ".
+func FormatEnergy(kcal float64, unit string) string {
+	return fmt.Sprintf("%.0f %s", math.Round(FromKcal(kcal, unit)), EnergyName(unit))
+}
@@ -0,0 +1,88 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEnergyRoundTrip(t *testing.T) {
+	for _, unit := range []string{Kcal, Kilojoules, "KJ"} {
+		for _, kcal := range []float64{0, 1, 250, 1234.5} {
+			if got := ToKcal(FromKcal(kcal, unit), unit); math.Abs(got-kcal) > 1e-9 {
+				t.Errorf("ToKcal(FromKcal(%v, %q)) = %v", kcal, unit, got)
+			}
+		}
+	}
+}
+
+func TestFormatEnergy(t *testing.T) {
+	tests := []struct {
+		kcal float64
+		unit string
+		want string
+	}{
+		{250, Kcal, "250 calories"},
+		{250, Kilojoules, "1046 kJ"},
+		{99.5, Kcal, "100 calories"},
+		{99.4, Kcal, "99 calories"},
+		// 0.119 kcal is 0.498 kJ, which rounds down
+		{0.119, Kilojoules, "0 kJ"},
+		{0.12, Kilojoules, "1 kJ"},
+	}
+
+	for _, test := range tests {
+		if got := FormatEnergy(test.kcal, test.unit); got != test.want {
+			t.Errorf("FormatEnergy(%v, %q) = %q, want %q", test.kcal, test.unit, got, test.want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  float64
+		from    string
+		to      string
+		density float64
+		want    float64
+		wantErr bool
+	}{
+		{name: "g to oz", amount: 100, from: "g", to: "oz", want: 3.527396},
+		{name: "oz to g", amount: 1, from: "oz", to: "g", want: 28.349523},
+		{name: "lb to kg", amount: 1, from: "lb", to: "kg", want: 0.453592},
+		{name: "cup to ml", amount: 1, from: "cup", to: "ml", want: 240},
+		{name: "ml to g", amount: 100, from: "ml", to: "g", density: 1.03, want: 103},
+		{name: "oz to ml", amount: 2, from: "oz", to: "ml", density: 0.91, want: 62.306644},
+		{name: "g to ml without a density", amount: 100, from: "g", to: "ml", wantErr: true},
+		{name: "unknown unit", amount: 1, from: "stone", to: "g", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Convert(test.amount, test.from, test.to, test.density)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Convert() error = %v, want error %v", err, test.wantErr)
+			}
+			if math.Abs(got-test.want) > 1e-6 {
+				t.Errorf("Convert() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	pairs := [][2]string{{"g", "oz"}, {"g", "ml"}, {"oz", "ml"}, {"kg", "lb"}, {"ml", "floz"}, {"cup", "tbsp"}}
+	for _, pair := range pairs {
+		there, err := Convert(123.4, pair[0], pair[1], 1.08)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := Convert(there, pair[1], pair[0], 1.08)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(back-123.4) > 1e-9 {
+			t.Errorf("123.4 %v to %v and back = %v", pair[0], pair[1], back)
+		}
+	}
+}